@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,16 +22,21 @@ var Configuration *Config
 
 type Config struct {
 	id          types.UUID
+	leaderMu    sync.RWMutex
 	leaderUuid  types.UUID
+	leaderTerm  int64
+	leaderSince time.Time
 	baseDns     string
 	towersQueue string
 	auditQueue  string
+	adminToken  string
 
 	db       *pgxpool.Pool
 	rabbitmq *amqp.Channel
 	email    types.EmailConfig
 
-	uptime time.Time
+	uptime           time.Time
+	electionPriority int
 
 	maxLeaderFailures    int
 	maxStructureFailures int
@@ -39,6 +45,22 @@ type Config struct {
 	heartbeatTimeout     time.Duration
 	renewLockInterval    time.Duration
 	renewLockTimeout     time.Duration
+	quorumSize           int
+	reservationTTL       time.Duration
+	idempotencyTTL       time.Duration
+	dataDir              string
+	cacheSnapshot        bool
+	reconcileInterval    time.Duration
+	reconcilePolicy      types.ReconcilePolicy
+
+	propagationParallelism int
+	propagationTimeout     time.Duration
+	propagationRetries     int
+
+	consensusMode types.ConsensusMode
+	raftPort      string
+	raftDir       string
+	raftPeers     []types.UUID
 }
 
 func (c *Config) GetId() types.UUID {
@@ -73,22 +95,96 @@ func (c *Config) GetBaseDns() string {
 	return c.baseDns
 }
 
+func (c *Config) GetAdminToken() string {
+	return c.adminToken
+}
+
 func (c *Config) GetLeaderUUID() types.UUID {
+	c.leaderMu.RLock()
+	defer c.leaderMu.RUnlock()
+
 	return c.leaderUuid
 }
 
 func (c *Config) GetLeaderUUIDAsString() string {
-	return c.leaderUuid.String()
+	leaderUuid := c.GetLeaderUUID()
+	return leaderUuid.String()
 }
 
 func (c *Config) SetLeaderUUID(id types.UUID) {
+	c.leaderMu.Lock()
+	defer c.leaderMu.Unlock()
+
+	c.setLeaderUUID(id)
+}
+
+func (c *Config) setLeaderUUID(id types.UUID) {
+	if id != c.leaderUuid {
+		c.leaderSince = time.Now()
+	}
 	c.leaderUuid = id
 }
 
+func (c *Config) GetLeaderSince() time.Time {
+	c.leaderMu.RLock()
+	defer c.leaderMu.RUnlock()
+
+	return c.leaderSince
+}
+
+func (c *Config) GetLeaderTerm() int64 {
+	c.leaderMu.RLock()
+	defer c.leaderMu.RUnlock()
+
+	return c.leaderTerm
+}
+
+func (c *Config) GetLeader() (types.UUID, int64) {
+	c.leaderMu.RLock()
+	defer c.leaderMu.RUnlock()
+
+	return c.leaderUuid, c.leaderTerm
+}
+
+// SetLeader sets the leader and its term together, so the term used as
+// fencing token never pairs with another leader.
+func (c *Config) SetLeader(id types.UUID, term int64) {
+	c.leaderMu.Lock()
+	defer c.leaderMu.Unlock()
+
+	c.setLeaderUUID(id)
+	c.leaderTerm = term
+}
+
+func (c *Config) SetLeaderIfNewer(id types.UUID, term int64) bool {
+	c.leaderMu.Lock()
+	defer c.leaderMu.Unlock()
+
+	if term < c.leaderTerm || (term == c.leaderTerm && id == c.leaderUuid) {
+		return false
+	}
+
+	c.setLeaderUUID(id)
+	c.leaderTerm = term
+	return true
+}
+
 func (c *Config) GetUptimeSeconds() float64 {
 	return time.Since(c.uptime).Seconds()
 }
 
+func (c *Config) GetElectionPriority() int {
+	return c.electionPriority
+}
+
+func (c *Config) GetCandidate() types.Candidate {
+	return types.Candidate{
+		UUID:      c.id,
+		Priority:  c.electionPriority,
+		StartedAt: c.uptime.Unix(),
+	}
+}
+
 func (c *Config) GetMaxLeaderFailures() int {
 	return c.maxLeaderFailures
 }
@@ -101,6 +197,18 @@ func (c *Config) GetPropagationInterval() time.Duration {
 	return c.propagationInterval
 }
 
+func (c *Config) GetPropagationParallelism() int {
+	return c.propagationParallelism
+}
+
+func (c *Config) GetPropagationTimeout() time.Duration {
+	return c.propagationTimeout
+}
+
+func (c *Config) GetPropagationRetries() int {
+	return c.propagationRetries
+}
+
 func (c *Config) GetHeartbeatInterval() time.Duration {
 	return c.heartbeatInterval
 }
@@ -117,8 +225,53 @@ func (c *Config) GetRenewLockTimeout() time.Duration {
 	return c.renewLockTimeout
 }
 
+// GetQuorumSize returns zero when a majority of the towers is required.
+func (c *Config) GetQuorumSize() int {
+	return c.quorumSize
+}
+
+func (c *Config) GetReservationTTL() time.Duration {
+	return c.reservationTTL
+}
+
+func (c *Config) GetIdempotencyTTL() time.Duration {
+	return c.idempotencyTTL
+}
+
+func (c *Config) GetReconcileInterval() time.Duration {
+	return c.reconcileInterval
+}
+
+func (c *Config) GetReconcilePolicy() types.ReconcilePolicy {
+	return c.reconcilePolicy
+}
+
+func (c *Config) GetDataDir() string {
+	return c.dataDir
+}
+
+func (c *Config) IsCacheSnapshotEnabled() bool {
+	return c.cacheSnapshot
+}
+
+func (c *Config) GetConsensusMode() types.ConsensusMode {
+	return c.consensusMode
+}
+
+func (c *Config) GetRaftPort() string {
+	return c.raftPort
+}
+
+func (c *Config) GetRaftDir() string {
+	return c.raftDir
+}
+
+func (c *Config) GetRaftPeers() []types.UUID {
+	return c.raftPeers
+}
+
 func (c *Config) IsLeader() bool {
-	return c.leaderUuid == c.id
+	return c.GetLeaderUUID() == c.id
 }
 
 func InitConfig(ctx context.Context) {
@@ -156,6 +309,32 @@ func InitConfig(ctx context.Context) {
 
 	propagationInterval := time.Duration(pinterval) * time.Second
 
+	propagationParallelism := utils.DefaultPropagationParallelism
+	if parallelism := os.Getenv(utils.PropagationParallelismEnv); parallelism != "" {
+		propagationParallelism, err = strconv.Atoi(parallelism)
+		if err != nil || propagationParallelism <= 0 {
+			log.Fatalf("failed to parse propagation parallelism env: must be a positive integer")
+		}
+	}
+
+	propagationTimeout := utils.DefaultPropagationTimeout
+	if timeout := os.Getenv(utils.PropagationTimeoutEnv); timeout != "" {
+		seconds, err := strconv.Atoi(timeout)
+		if err != nil || seconds <= 0 {
+			log.Fatalf("failed to parse propagation timeout env: must be a positive integer")
+		}
+
+		propagationTimeout = time.Duration(seconds) * time.Second
+	}
+
+	propagationRetries := utils.DefaultPropagationRetries
+	if retries := os.Getenv(utils.PropagationRetriesEnv); retries != "" {
+		propagationRetries, err = strconv.Atoi(retries)
+		if err != nil || propagationRetries < 0 {
+			log.Fatalf("failed to parse propagation retries env: must be a non-negative integer")
+		}
+	}
+
 	hinterval, err := strconv.Atoi(os.Getenv(utils.HeartbeatIntervalEnv))
 	if err != nil {
 		log.Fatalf("failed to parse propagation interval env: %v", err)
@@ -184,15 +363,100 @@ func InitConfig(ctx context.Context) {
 
 	renewLockTimeout := time.Duration(ltimeout) * time.Second
 
+	quorumSize := 0
+	if size := os.Getenv(utils.QuorumSizeEnv); size != "" {
+		quorumSize, err = strconv.Atoi(size)
+		if err != nil || quorumSize < 0 {
+			log.Fatalf("failed to parse quorum size env: must be a non-negative integer")
+		}
+	}
+
+	reservationTTL := utils.DefaultReservationTTL
+	if ttl := os.Getenv(utils.ReservationTTLEnv); ttl != "" {
+		seconds, err := strconv.Atoi(ttl)
+		if err != nil {
+			log.Fatalf("failed to parse reservation ttl env: %v", err)
+		}
+
+		reservationTTL = time.Duration(seconds) * time.Second
+	}
+
+	idempotencyTTL := utils.DefaultIdempotencyTTL
+	if ttl := os.Getenv(utils.IdempotencyTTLEnv); ttl != "" {
+		seconds, err := strconv.Atoi(ttl)
+		if err != nil {
+			log.Fatalf("failed to parse idempotency ttl env: %v", err)
+		}
+
+		idempotencyTTL = time.Duration(seconds) * time.Second
+	}
+
+	dataDir := os.Getenv(utils.DataDirEnv)
+	if dataDir == "" {
+		dataDir = utils.DefaultDataDir
+	}
+
+	cacheSnapshot := false
+	if enabled := os.Getenv(utils.CacheSnapshotEnv); enabled != "" {
+		cacheSnapshot, err = strconv.ParseBool(enabled)
+		if err != nil {
+			log.Fatalf("failed to parse cache snapshot env: %v", err)
+		}
+	}
+
+	reconcileInterval := utils.DefaultReconcileInterval
+	if interval := os.Getenv(utils.ReconcileIntervalEnv); interval != "" {
+		seconds, err := strconv.Atoi(interval)
+		if err != nil {
+			log.Fatalf("failed to parse reconcile interval env: %v", err)
+		}
+
+		reconcileInterval = time.Duration(seconds) * time.Second
+	}
+
+	reconcilePolicy := types.ReconcilePolicy(os.Getenv(utils.ReconcilePolicyEnv))
+	switch reconcilePolicy {
+	case "":
+		reconcilePolicy = types.ReportReconcilePolicy
+	case types.ReportReconcilePolicy, types.RepairReconcilePolicy:
+	default:
+		log.Fatalf("invalid reconcile policy %s in env %s", reconcilePolicy, utils.ReconcilePolicyEnv)
+	}
+
+	electionPriority := 0
+	if priority := os.Getenv(utils.ElectionPriorityEnv); priority != "" {
+		electionPriority, err = strconv.Atoi(priority)
+		if err != nil {
+			log.Fatalf("failed to parse election priority env: %v", err)
+		}
+	}
+
+	consensusMode := types.ConsensusMode(os.Getenv(utils.ConsensusModeEnv))
+	if consensusMode == "" {
+		consensusMode = types.LockConsensusMode
+	}
+
+	var raftPort, raftDir string
+	var raftPeers []types.UUID
+	switch consensusMode {
+	case types.LockConsensusMode:
+	case types.RaftConsensusMode:
+		raftPort, raftDir, raftPeers = getRaftConfig()
+	default:
+		log.Fatalf("invalid consensus mode %s in env %s", consensusMode, utils.ConsensusModeEnv)
+	}
+
 	Configuration = &Config{
 		id:                   types.UUID(id),
 		baseDns:              dns,
 		towersQueue:          towersQueue,
 		auditQueue:           auditQueue,
+		adminToken:           os.Getenv(utils.AdminTokenEnv),
 		db:                   pool,
 		rabbitmq:             channel,
 		email:                email,
 		uptime:               time.Now(),
+		electionPriority:     electionPriority,
 		maxLeaderFailures:    maxLeaderFailures,
 		maxStructureFailures: maxStructureFailures,
 		propagationInterval:  propagationInterval,
@@ -200,6 +464,21 @@ func InitConfig(ctx context.Context) {
 		heartbeatTimeout:     heartbeatTimeout,
 		renewLockInterval:    renewLockInterval,
 		renewLockTimeout:     renewLockTimeout,
+		quorumSize:           quorumSize,
+		reservationTTL:       reservationTTL,
+		idempotencyTTL:       idempotencyTTL,
+		dataDir:              dataDir,
+		cacheSnapshot:        cacheSnapshot,
+		reconcileInterval:    reconcileInterval,
+		reconcilePolicy:      reconcilePolicy,
+		consensusMode:        consensusMode,
+		raftPort:             raftPort,
+		raftDir:              raftDir,
+		raftPeers:            raftPeers,
+
+		propagationParallelism: propagationParallelism,
+		propagationTimeout:     propagationTimeout,
+		propagationRetries:     propagationRetries,
 	}
 }
 
@@ -232,3 +511,27 @@ func getEmailConfig() types.EmailConfig {
 		Recipients: strings.Split(os.Getenv(utils.EmailRecipientsEnv), ","),
 	}
 }
+
+func getRaftConfig() (string, string, []types.UUID) {
+	port := os.Getenv(utils.RaftPortEnv)
+	if _, err := strconv.Atoi(port); err != nil {
+		log.Fatalf("failed to parse raft port env: %v", err)
+	}
+
+	dir := os.Getenv(utils.RaftDirEnv)
+	if dir == "" {
+		log.Fatalf("env %s is required in raft consensus mode", utils.RaftDirEnv)
+	}
+
+	var peers []types.UUID
+	for _, peer := range strings.Split(os.Getenv(utils.RaftPeersEnv), ",") {
+		id, err := uuid.Parse(strings.TrimSpace(peer))
+		if err != nil {
+			log.Fatalf("invalid peer %s in env %s: %v", peer, utils.RaftPeersEnv, err)
+		}
+
+		peers = append(peers, types.UUID(id))
+	}
+
+	return port, dir, peers
+}
@@ -5,34 +5,45 @@ import (
 	"log"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/tower/leader"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/tower/minion"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 )
 
-var activeRoleCleanup func()
+var (
+	activeRole        types.Role
+	activeRoleCleanup func()
+)
 
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	config.InitConfig(ctx)
-	leaderUuid := leaderelection.AcquireLockIfEmptyAndReturnLeaderUUID(ctx)
-	config.Configuration.SetLeaderUUID(leaderUuid)
-
-	if config.Configuration.IsLeader() {
-		log.Println("starting initial role: LEADER")
-		activeRoleCleanup = leader.InitLeader(ctx)
-	} else {
-		log.Println("starting initial role: MINION")
-		activeRoleCleanup = minion.InitMinion(ctx) 
+
+	var elector leaderelection.Elector
+	switch config.Configuration.GetConsensusMode() {
+	case types.RaftConsensusMode:
+		consensus.InitReplica()
+		elector = leaderelection.NewRaftElector(consensus.Replica)
+	default:
+		elector = leaderelection.NewLockElector()
+	}
+
+	if _, err := elector.Campaign(ctx, nil); err != nil {
+		log.Fatalf("failed to run initial election: %v", err)
 	}
 
 main_loop:
 	for {
 		select {
-		case role := <-leaderelection.ChangeRoleCh:
+		case role := <-elector.Leadership():
+			if activeRoleCleanup != nil && role == activeRole {
+				break
+			}
+
 			log.Printf("received request to change role...")
 
 			if activeRoleCleanup != nil {
@@ -40,15 +51,17 @@ main_loop:
 				activeRoleCleanup() 
 			}
 
+			activeRole = role
 			if role == types.Leader {
 				log.Printf("role requested: LEADER")
-				activeRoleCleanup = leader.InitLeader(ctx)
+				activeRoleCleanup = leader.InitLeader(ctx, elector)
 				break
 			}
 
 			if role == types.Minion {
 				log.Printf("role requested: MINION")
-				activeRoleCleanup = minion.InitMinion(ctx)
+				syncLeader(ctx, elector)
+				activeRoleCleanup = minion.InitMinion(ctx, elector)
 			}
 		case <-ctx.Done():
 			break main_loop
@@ -58,4 +71,29 @@ main_loop:
 	if activeRoleCleanup != nil {
 		activeRoleCleanup()
 	}
+
+	config.CloseRabbitMQ()
+
+	if consensus.Enabled() {
+		if err := consensus.Replica.Shutdown(); err != nil {
+			log.Printf("failed to shutdown raft replica: %v", err)
+		}
+	}
+}
+
+func syncLeader(ctx context.Context, elector leaderelection.Elector) {
+	leaderUuid, term, err := elector.Leader(ctx)
+	if err != nil {
+		log.Printf("failed to get current leader: %v", err)
+		if config.Configuration.IsLeader() {
+			config.Configuration.SetLeaderUUID(types.UUID{})
+		}
+		return
+	}
+
+	if term < config.Configuration.GetLeaderTerm() {
+		return
+	}
+
+	config.Configuration.SetLeader(leaderUuid, term)
 }
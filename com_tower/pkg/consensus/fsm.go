@@ -0,0 +1,449 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+)
+
+type commandType string
+
+const (
+	acquireSlotCommand        commandType = "acquire_slot"
+	preemptSlotCommand        commandType = "preempt_slot"
+	occupySlotCommand         commandType = "occupy_slot"
+	releaseSlotCommand        commandType = "release_slot"
+	expireReservationsCommand commandType = "expire_reservations"
+	enqueueWaitlistCommand    commandType = "enqueue_waitlist"
+	leaveWaitlistCommand      commandType = "leave_waitlist"
+	withdrawOfferCommand      commandType = "withdraw_offer"
+	syncTowersCommand         commandType = "sync_towers"
+	syncStructuresCommand     commandType = "sync_structures"
+)
+
+// command times are set by the leader when proposing it, so every replica
+// applies it the same way.
+type command struct {
+	Type          commandType               `json:"type"`
+	Slot          *types.AcquireSlotRequest `json:"slot,omitempty"`
+	ReservedUntil int64                     `json:"reserved_until,omitempty"`
+	Now           int64                     `json:"now,omitempty"`
+	Waitlist      *types.WaitlistEntry      `json:"waitlist,omitempty"`
+	Vehicle       types.UUID                `json:"vehicle,omitempty"`
+	Towers        []types.Tower             `json:"towers,omitempty"`
+	Structures    *types.Structures         `json:"structures,omitempty"`
+}
+
+type applyResult struct {
+	result    types.AcquireSlotResultType
+	expired   []types.SlotReservation
+	displaced *types.SlotReservation
+	err       error
+}
+
+type state struct {
+	Towers       []types.Tower         `json:"towers"`
+	Structures   types.Structures      `json:"structures"`
+	Slots        map[string]types.UUID `json:"slots"`
+	Reservations map[string]int64      `json:"reservations"`
+	Waitlist     []types.WaitlistEntry `json:"waitlist"`
+	Offers       map[string]offer      `json:"offers"`
+}
+
+// offer expires in case the leader offering the slot goes away before
+// granting it.
+type offer struct {
+	Vehicle types.UUID `json:"vehicle"`
+	Until   int64      `json:"until"`
+}
+
+type fsm struct {
+	mu    sync.RWMutex
+	state state
+}
+
+func newFSM() *fsm {
+	return &fsm{
+		state: state{
+			Towers:       []types.Tower{},
+			Slots:        map[string]types.UUID{},
+			Reservations: map[string]int64{},
+			Offers:       map[string]offer{},
+		},
+	}
+}
+
+func slotKey(structureUuid types.UUID, slotType types.SlotType, slotNumber int) string {
+	return fmt.Sprintf("%s/%s/%d", structureUuid.String(), slotType, slotNumber)
+}
+
+func (f *fsm) Apply(entry *raft.Log) interface{} {
+	var cmd command
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+		return applyResult{err: fmt.Errorf("failed to unmarshal raft command: %w", err)}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch cmd.Type {
+	case acquireSlotCommand:
+		return f.acquireSlot(*cmd.Slot, cmd.ReservedUntil)
+	case preemptSlotCommand:
+		return f.preemptSlot(*cmd.Slot, cmd.ReservedUntil)
+	case occupySlotCommand:
+		return f.occupySlot(*cmd.Slot)
+	case releaseSlotCommand:
+		return f.releaseSlot(*cmd.Slot, cmd.ReservedUntil)
+	case expireReservationsCommand:
+		return f.expireReservations(cmd.Now, cmd.ReservedUntil)
+	case enqueueWaitlistCommand:
+		f.enqueueWaitlist(*cmd.Waitlist)
+	case leaveWaitlistCommand:
+		f.leaveWaitlist(cmd.Vehicle)
+	case withdrawOfferCommand:
+		f.withdrawOffer(*cmd.Slot)
+	case syncTowersCommand:
+		f.state.Towers = cmd.Towers
+	case syncStructuresCommand:
+		f.state.Structures = *cmd.Structures
+	default:
+		return applyResult{err: fmt.Errorf("unknown raft command %s", cmd.Type)}
+	}
+
+	return applyResult{}
+}
+
+func (f *fsm) acquireSlot(request types.AcquireSlotRequest, reservedUntil int64) applyResult {
+	if !f.slotExists(request.StructureUUID, request.SlotType, request.SlotNumber) {
+		return applyResult{err: fmt.Errorf("slot %s %d not found in structure %s: %w", request.SlotType, request.SlotNumber, request.StructureUUID.String(), utils.ErrInvalidInput)}
+	}
+
+	key := slotKey(request.StructureUUID, request.SlotType, request.SlotNumber)
+	if holder, ok := f.state.Slots[key]; ok {
+		if holder == request.VehicleUUID {
+			return applyResult{result: types.AcquiredAcquireSlotResultType}
+		}
+
+		return applyResult{result: types.UnavailableAcquireSlotResultType}
+	}
+
+	// a slot released while vehicles wait is held back for the vehicle it was
+	// offered to, unless the request jumps the waitlist
+	if offered, ok := f.state.Offers[key]; ok && offered.Vehicle != request.VehicleUUID && !request.Priority.JumpsWaitlist() {
+		return applyResult{result: types.WaitlistedAcquireSlotResultType}
+	}
+
+	f.assignSlot(key, request.VehicleUUID, reservedUntil)
+	return applyResult{result: types.AcquiredAcquireSlotResultType}
+}
+
+func (f *fsm) preemptSlot(request types.AcquireSlotRequest, reservedUntil int64) applyResult {
+	key := slotKey(request.StructureUUID, request.SlotType, request.SlotNumber)
+	holder, ok := f.state.Slots[key]
+	if !ok {
+		return applyResult{result: types.UnavailableAcquireSlotResultType}
+	}
+
+	if holder == request.VehicleUUID {
+		return applyResult{result: types.AcquiredAcquireSlotResultType}
+	}
+
+	if _, reserved := f.state.Reservations[key]; !reserved {
+		return applyResult{result: types.UnavailableAcquireSlotResultType}
+	}
+
+	displaced, ok := f.reservation(key)
+	if !ok {
+		return applyResult{err: fmt.Errorf("failed to rebuild reservation of slot %s", key)}
+	}
+
+	f.assignSlot(key, request.VehicleUUID, reservedUntil)
+	return applyResult{result: types.AcquiredAcquireSlotResultType, displaced: &displaced}
+}
+
+func (f *fsm) assignSlot(key string, vehicleUuid types.UUID, reservedUntil int64) {
+	f.leaveWaitlist(vehicleUuid)
+	delete(f.state.Offers, key)
+
+	// a vehicle holds at most one slot, as vehicles.current_slot_id does
+	for slot, holder := range f.state.Slots {
+		if holder == vehicleUuid {
+			delete(f.state.Slots, slot)
+			delete(f.state.Reservations, slot)
+		}
+	}
+
+	f.state.Slots[key] = vehicleUuid
+	f.state.Reservations[key] = reservedUntil
+}
+
+func (f *fsm) occupySlot(request types.AcquireSlotRequest) applyResult {
+	key := slotKey(request.StructureUUID, request.SlotType, request.SlotNumber)
+	if holder, ok := f.state.Slots[key]; !ok || holder != request.VehicleUUID {
+		return applyResult{err: errors.New("slot is not held by vehicle, slot was not occupied")}
+	}
+
+	delete(f.state.Reservations, key)
+	return applyResult{}
+}
+
+func (f *fsm) releaseSlot(request types.AcquireSlotRequest, offeredUntil int64) applyResult {
+	key := slotKey(request.StructureUUID, request.SlotType, request.SlotNumber)
+	if holder, ok := f.state.Slots[key]; !ok || holder != request.VehicleUUID {
+		return applyResult{err: errors.New("slot is not held by vehicle, slot was not released")}
+	}
+
+	delete(f.state.Slots, key)
+	delete(f.state.Reservations, key)
+	f.offerSlot(key, request.StructureUUID, request.SlotType, offeredUntil)
+	return applyResult{}
+}
+
+func (f *fsm) expireReservations(now int64, offeredUntil int64) applyResult {
+	for key, offered := range f.state.Offers {
+		if offered.Until <= now {
+			delete(f.state.Offers, key)
+		}
+	}
+
+	// slots are freed in key order, so every replica offers them to the same
+	// waiting vehicles
+	var expired []types.SlotReservation
+	for _, key := range slices.Sorted(maps.Keys(f.state.Reservations)) {
+		if f.state.Reservations[key] > now {
+			continue
+		}
+
+		reservation, ok := f.reservation(key)
+		if !ok {
+			continue
+		}
+
+		delete(f.state.Slots, key)
+		delete(f.state.Reservations, key)
+		f.offerSlot(key, reservation.StructureUUID, reservation.SlotType, offeredUntil)
+		expired = append(expired, reservation)
+	}
+
+	return applyResult{expired: expired}
+}
+
+// enqueueWaitlist keeps the place of a vehicle already waiting for the same
+// slots.
+func (f *fsm) enqueueWaitlist(entry types.WaitlistEntry) {
+	index := slices.IndexFunc(f.state.Waitlist, func(queued types.WaitlistEntry) bool { return queued.VehicleUUID == entry.VehicleUUID })
+	if index >= 0 {
+		queued := f.state.Waitlist[index]
+		if queued.StructureUUID == entry.StructureUUID && queued.SlotType == entry.SlotType && queued.Priority == entry.Priority {
+			return
+		}
+
+		f.state.Waitlist = slices.Delete(f.state.Waitlist, index, index+1)
+	}
+
+	position := slices.IndexFunc(f.state.Waitlist, func(queued types.WaitlistEntry) bool { return queued.Priority < entry.Priority })
+	if position < 0 {
+		position = len(f.state.Waitlist)
+	}
+
+	f.state.Waitlist = slices.Insert(f.state.Waitlist, position, entry)
+}
+
+func (f *fsm) leaveWaitlist(vehicleUuid types.UUID) {
+	f.state.Waitlist = slices.DeleteFunc(f.state.Waitlist, func(queued types.WaitlistEntry) bool { return queued.VehicleUUID == vehicleUuid })
+	maps.DeleteFunc(f.state.Offers, func(_ string, offered offer) bool { return offered.Vehicle == vehicleUuid })
+}
+
+func (f *fsm) offerSlot(key string, structureUuid types.UUID, slotType types.SlotType, offeredUntil int64) {
+	for _, queued := range f.state.Waitlist {
+		if queued.StructureUUID != structureUuid || queued.SlotType != slotType {
+			continue
+		}
+
+		if _, offered := f.offeredSlot(queued.VehicleUUID); offered {
+			continue
+		}
+
+		f.state.Offers[key] = offer{Vehicle: queued.VehicleUUID, Until: offeredUntil}
+		return
+	}
+}
+
+func (f *fsm) withdrawOffer(request types.AcquireSlotRequest) {
+	key := slotKey(request.StructureUUID, request.SlotType, request.SlotNumber)
+	if offered, ok := f.state.Offers[key]; ok && offered.Vehicle == request.VehicleUUID {
+		delete(f.state.Offers, key)
+	}
+}
+
+func (f *fsm) offeredSlot(vehicleUuid types.UUID) (string, bool) {
+	for key, offered := range f.state.Offers {
+		if offered.Vehicle == vehicleUuid {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+func (f *fsm) offeredTo(key string) (types.WaitlistEntry, bool) {
+	offered, ok := f.state.Offers[key]
+	if !ok {
+		return types.WaitlistEntry{}, false
+	}
+
+	index := slices.IndexFunc(f.state.Waitlist, func(queued types.WaitlistEntry) bool { return queued.VehicleUUID == offered.Vehicle })
+	if index < 0 {
+		return types.WaitlistEntry{}, false
+	}
+
+	return f.state.Waitlist[index], true
+}
+
+func (f *fsm) waitlistPosition(vehicleUuid types.UUID) int {
+	index := slices.IndexFunc(f.state.Waitlist, func(queued types.WaitlistEntry) bool { return queued.VehicleUUID == vehicleUuid })
+	if index < 0 {
+		return 0
+	}
+
+	entry := f.state.Waitlist[index]
+	position := 0
+	for _, queued := range f.state.Waitlist[:index+1] {
+		if queued.StructureUUID == entry.StructureUUID && queued.SlotType == entry.SlotType {
+			position++
+		}
+	}
+
+	return position
+}
+
+func (f *fsm) reservation(key string) (types.SlotReservation, bool) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return types.SlotReservation{}, false
+	}
+
+	structureUuid, err := uuid.Parse(parts[0])
+	if err != nil {
+		return types.SlotReservation{}, false
+	}
+
+	slotNumber, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return types.SlotReservation{}, false
+	}
+
+	reservation := types.SlotReservation{
+		VehicleUUID:   f.state.Slots[key],
+		StructureUUID: types.UUID(structureUuid),
+		SlotType:      types.SlotType(parts[1]),
+		SlotNumber:    slotNumber,
+	}
+
+	if slices.ContainsFunc(f.state.Structures.Platforms, func(platform types.Platform) bool { return platform.UUID == reservation.StructureUUID }) {
+		reservation.StructureType = types.PlatformStructureType
+	} else if slices.ContainsFunc(f.state.Structures.Centrals, func(central types.Central) bool { return central.UUID == reservation.StructureUUID }) {
+		reservation.StructureType = types.CentralStructureType
+	}
+
+	return reservation, true
+}
+
+func (f *fsm) slotExists(structureUuid types.UUID, slotType types.SlotType, slotNumber int) bool {
+	return slotNumber > 0 && slotNumber <= f.slotCount(structureUuid, slotType)
+}
+
+func (f *fsm) slotCount(structureUuid types.UUID, slotType types.SlotType) int {
+	var slots *types.StructureSlots
+	for _, platform := range f.state.Structures.Platforms {
+		if platform.UUID == structureUuid {
+			slots = &platform.Slots
+		}
+	}
+
+	for _, central := range f.state.Structures.Centrals {
+		if central.UUID == structureUuid {
+			slots = &central.Slots
+		}
+	}
+
+	if slots == nil {
+		return 0
+	}
+
+	switch slotType {
+	case types.DockSlotType:
+		return slots.DocksQtt
+	case types.HelipadSlotType:
+		return slots.HelipadsQtt
+	default:
+		return 0
+	}
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	data, err := json.Marshal(f.state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal raft state: %w", err)
+	}
+
+	return snapshot{data: data}, nil
+}
+
+func (f *fsm) Restore(reader io.ReadCloser) error {
+	defer reader.Close()
+
+	restored := state{}
+	if err := json.NewDecoder(reader).Decode(&restored); err != nil {
+		return fmt.Errorf("failed to decode raft snapshot: %w", err)
+	}
+
+	if restored.Slots == nil {
+		restored.Slots = map[string]types.UUID{}
+	}
+
+	// snapshots taken before reservations existed only hold occupied slots
+	if restored.Reservations == nil {
+		restored.Reservations = map[string]int64{}
+	}
+
+	if restored.Offers == nil {
+		restored.Offers = map[string]offer{}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.state = restored
+	return nil
+}
+
+type snapshot struct {
+	data []byte
+}
+
+func (s snapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s.data); err != nil {
+		sink.Cancel()
+		return fmt.Errorf("failed to write raft snapshot: %w", err)
+	}
+
+	return sink.Close()
+}
+
+func (s snapshot) Release() {}
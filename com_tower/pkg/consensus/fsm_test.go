@@ -0,0 +1,328 @@
+package consensus
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+)
+
+const testDocks = 8
+
+func newVehicle() types.UUID {
+	return types.UUID(uuid.New())
+}
+
+// newTestFSM returns an fsm holding a single platform with testDocks docks.
+func newTestFSM(t *testing.T, platform types.UUID) *fsm {
+	t.Helper()
+
+	f := newFSM()
+	structures := types.Structures{
+		Platforms: []types.Platform{{UUID: platform, Structure: types.Structure{Slots: types.StructureSlots{DocksQtt: testDocks}}}},
+	}
+
+	apply(t, f, command{Type: syncStructuresCommand, Structures: &structures})
+	return f
+}
+
+func apply(t *testing.T, f *fsm, cmd command) applyResult {
+	t.Helper()
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("failed to marshal command %s: %v", cmd.Type, err)
+	}
+
+	result, ok := f.Apply(&raft.Log{Data: data}).(applyResult)
+	if !ok {
+		t.Fatalf("command %s did not return an apply result", cmd.Type)
+	}
+
+	return result
+}
+
+func dock(vehicle types.UUID, platform types.UUID, number int) *types.AcquireSlotRequest {
+	return &types.AcquireSlotRequest{
+		VehicleUUID:          vehicle,
+		StructureUUID:        platform,
+		StructureSlotRequest: types.StructureSlotRequest{SlotNumber: number, SlotType: types.DockSlotType},
+	}
+}
+
+func waitFor(vehicle types.UUID, platform types.UUID, priority int) *types.WaitlistEntry {
+	return &types.WaitlistEntry{VehicleUUID: vehicle, StructureUUID: platform, StructureType: types.PlatformStructureType, SlotType: types.DockSlotType, Priority: priority}
+}
+
+func TestExpireReservationsOffersTheSameSlotsOnEveryReplica(t *testing.T) {
+	platform := newVehicle()
+	holders := make([]types.UUID, testDocks)
+	for i := range holders {
+		holders[i] = newVehicle()
+	}
+
+	waiting := []types.UUID{newVehicle(), newVehicle(), newVehicle()}
+
+	commands := []command{}
+	for i, holder := range holders {
+		commands = append(commands, command{Type: acquireSlotCommand, Slot: dock(holder, platform, i+1), ReservedUntil: 100})
+	}
+
+	for _, vehicle := range waiting {
+		commands = append(commands, command{Type: enqueueWaitlistCommand, Waitlist: waitFor(vehicle, platform, 0)})
+	}
+
+	commands = append(commands, command{Type: expireReservationsCommand, Now: 100, ReservedUntil: 200})
+
+	// map iteration order differs between runs, so replay the log on a few
+	// pairs of replicas
+	for range 20 {
+		first, second := newTestFSM(t, platform), newTestFSM(t, platform)
+		for _, cmd := range commands {
+			apply(t, first, cmd)
+			apply(t, second, cmd)
+		}
+
+		if len(first.state.Offers) != len(waiting) {
+			t.Fatalf("expiring %d reservations offered %d slots to %d waiting vehicles", testDocks, len(first.state.Offers), len(waiting))
+		}
+
+		if !maps.Equal(first.state.Offers, second.state.Offers) {
+			t.Fatalf("replicas applying the same log offered different slots: %v and %v", first.state.Offers, second.state.Offers)
+		}
+
+		for i, vehicle := range waiting {
+			key := slotKey(platform, types.DockSlotType, i+1)
+			if offered := first.state.Offers[key]; offered.Vehicle != vehicle {
+				t.Fatalf("slot %s was offered to %s, want waiting vehicle %d", key, offered.Vehicle.String(), i)
+			}
+		}
+	}
+}
+
+func emergency(request *types.AcquireSlotRequest) *types.AcquireSlotRequest {
+	request.Priority = types.EmergencySlotPriority
+	return request
+}
+
+func TestFSMApply(t *testing.T) {
+	platform := newVehicle()
+	first, second, waiting, rescue := newVehicle(), newVehicle(), newVehicle(), newVehicle()
+
+	reserve := func(vehicle types.UUID, number int, until int64) command {
+		return command{Type: acquireSlotCommand, Slot: dock(vehicle, platform, number), ReservedUntil: until}
+	}
+
+	release := func(vehicle types.UUID, number int, offeredUntil int64) command {
+		return command{Type: releaseSlotCommand, Slot: dock(vehicle, platform, number), ReservedUntil: offeredUntil}
+	}
+
+	enqueue := command{Type: enqueueWaitlistCommand, Waitlist: waitFor(waiting, platform, 0)}
+
+	tests := []struct {
+		name      string
+		log       []command
+		cmd       command
+		result    types.AcquireSlotResultType
+		err       bool
+		holders   map[int]types.UUID
+		offers    map[int]types.UUID
+		displaced types.UUID
+		expired   []int
+	}{
+		{
+			name:    "acquire a free slot",
+			cmd:     reserve(first, 1, 100),
+			result:  types.AcquiredAcquireSlotResultType,
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "acquire a slot held by another vehicle",
+			log:     []command{reserve(first, 1, 100)},
+			cmd:     reserve(second, 1, 100),
+			result:  types.UnavailableAcquireSlotResultType,
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "acquire the slot already held",
+			log:     []command{reserve(first, 1, 100)},
+			cmd:     reserve(first, 1, 200),
+			result:  types.AcquiredAcquireSlotResultType,
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "acquire another slot moves the vehicle",
+			log:     []command{reserve(first, 1, 100)},
+			cmd:     reserve(first, 2, 100),
+			result:  types.AcquiredAcquireSlotResultType,
+			holders: map[int]types.UUID{2: first},
+		},
+		{
+			name:    "acquire a slot the structure does not have",
+			cmd:     reserve(first, testDocks+1, 100),
+			err:     true,
+			holders: map[int]types.UUID{},
+		},
+		{
+			name:    "release offers the slot to the waitlist head",
+			log:     []command{reserve(first, 1, 100), enqueue},
+			cmd:     release(first, 1, 200),
+			holders: map[int]types.UUID{},
+			offers:  map[int]types.UUID{1: waiting},
+		},
+		{
+			name:    "release a slot held by another vehicle",
+			log:     []command{reserve(first, 1, 100)},
+			cmd:     release(second, 1, 200),
+			err:     true,
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "an offered slot is held back from other vehicles",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     reserve(second, 1, 100),
+			result:  types.WaitlistedAcquireSlotResultType,
+			holders: map[int]types.UUID{},
+			offers:  map[int]types.UUID{1: waiting},
+		},
+		{
+			name:    "the vehicle offered a slot acquires it",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     reserve(waiting, 1, 300),
+			result:  types.AcquiredAcquireSlotResultType,
+			holders: map[int]types.UUID{1: waiting},
+		},
+		{
+			name:    "an emergency jumps the offer",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     command{Type: acquireSlotCommand, Slot: emergency(dock(rescue, platform, 1)), ReservedUntil: 100},
+			result:  types.AcquiredAcquireSlotResultType,
+			holders: map[int]types.UUID{1: rescue},
+		},
+		{
+			name:    "withdraw the offer",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     command{Type: withdrawOfferCommand, Slot: dock(waiting, platform, 1)},
+			holders: map[int]types.UUID{},
+		},
+		{
+			name:    "leaving the waitlist drops the offer",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     command{Type: leaveWaitlistCommand, Vehicle: waiting},
+			holders: map[int]types.UUID{},
+		},
+		{
+			name:      "preempt a reserved slot",
+			log:       []command{reserve(first, 1, 100)},
+			cmd:       command{Type: preemptSlotCommand, Slot: emergency(dock(rescue, platform, 1)), ReservedUntil: 100},
+			result:    types.AcquiredAcquireSlotResultType,
+			holders:   map[int]types.UUID{1: rescue},
+			displaced: first,
+		},
+		{
+			name:    "preempt an occupied slot",
+			log:     []command{reserve(first, 1, 100), {Type: occupySlotCommand, Slot: dock(first, platform, 1)}},
+			cmd:     command{Type: preemptSlotCommand, Slot: emergency(dock(rescue, platform, 1)), ReservedUntil: 100},
+			result:  types.UnavailableAcquireSlotResultType,
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "preempt a free slot",
+			cmd:     command{Type: preemptSlotCommand, Slot: emergency(dock(rescue, platform, 1)), ReservedUntil: 100},
+			result:  types.UnavailableAcquireSlotResultType,
+			holders: map[int]types.UUID{},
+		},
+		{
+			name:    "expire frees the expired reservations and offers them",
+			log:     []command{reserve(first, 1, 100), reserve(second, 2, 300), enqueue},
+			cmd:     command{Type: expireReservationsCommand, Now: 100, ReservedUntil: 200},
+			holders: map[int]types.UUID{2: second},
+			offers:  map[int]types.UUID{1: waiting},
+			expired: []int{1},
+		},
+		{
+			name:    "expire keeps occupied slots",
+			log:     []command{reserve(first, 1, 100), {Type: occupySlotCommand, Slot: dock(first, platform, 1)}},
+			cmd:     command{Type: expireReservationsCommand, Now: 100, ReservedUntil: 200},
+			holders: map[int]types.UUID{1: first},
+		},
+		{
+			name:    "expire keeps the offers still running",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     command{Type: expireReservationsCommand, Now: 150, ReservedUntil: 250},
+			holders: map[int]types.UUID{},
+			offers:  map[int]types.UUID{1: waiting},
+		},
+		{
+			name:    "expire drops the expired offers",
+			log:     []command{reserve(first, 1, 100), enqueue, release(first, 1, 200)},
+			cmd:     command{Type: expireReservationsCommand, Now: 200, ReservedUntil: 300},
+			holders: map[int]types.UUID{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFSM(t, platform)
+			for _, cmd := range tt.log {
+				if result := apply(t, f, cmd); result.err != nil {
+					t.Fatalf("failed to apply %s: %v", cmd.Type, result.err)
+				}
+			}
+
+			result := apply(t, f, tt.cmd)
+			if (result.err != nil) != tt.err {
+				t.Fatalf("%s returned error %v, want error %t", tt.cmd.Type, result.err, tt.err)
+			}
+
+			if result.result != tt.result {
+				t.Errorf("%s returned %q, want %q", tt.cmd.Type, result.result, tt.result)
+			}
+
+			holders := map[string]types.UUID{}
+			for number, vehicle := range tt.holders {
+				holders[slotKey(platform, types.DockSlotType, number)] = vehicle
+			}
+
+			if !maps.Equal(f.state.Slots, holders) {
+				t.Errorf("slots are held as %v, want %v", f.state.Slots, holders)
+			}
+
+			offers := map[string]types.UUID{}
+			for key, offered := range f.state.Offers {
+				offers[key] = offered.Vehicle
+			}
+
+			wantOffers := map[string]types.UUID{}
+			for number, vehicle := range tt.offers {
+				wantOffers[slotKey(platform, types.DockSlotType, number)] = vehicle
+			}
+
+			if !maps.Equal(offers, wantOffers) {
+				t.Errorf("slots are offered as %v, want %v", offers, wantOffers)
+			}
+
+			var displaced types.UUID
+			if result.displaced != nil {
+				displaced = result.displaced.VehicleUUID
+			}
+
+			if displaced != tt.displaced {
+				t.Errorf("displaced vehicle %s, want %s", displaced.String(), tt.displaced.String())
+			}
+
+			var expired []int
+			for _, reservation := range result.expired {
+				expired = append(expired, reservation.SlotNumber)
+			}
+
+			if !slices.Equal(expired, tt.expired) {
+				t.Errorf("expired slots %v, want %v", expired, tt.expired)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package consensus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+const (
+	applyTimeout     = 5 * time.Second
+	transportPool    = 3
+	transportTimeout = 10 * time.Second
+	snapshotsRetain  = 2
+)
+
+var ErrNoLeader = errors.New("raft cluster has no leader")
+
+var Replica *Node
+
+type Node struct {
+	raft *raft.Raft
+	fsm  *fsm
+}
+
+func Enabled() bool {
+	return Replica != nil
+}
+
+func InitReplica() {
+	dir := config.Configuration.GetRaftDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("failed to create raft directory %s: %v", dir, err)
+	}
+
+	store, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		log.Fatalf("failed to open raft log store: %v", err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(dir, snapshotsRetain, os.Stderr)
+	if err != nil {
+		log.Fatalf("failed to open raft snapshot store: %v", err)
+	}
+
+	advertise, err := net.ResolveTCPAddr("tcp", peerAddress(config.Configuration.GetId()))
+	if err != nil {
+		log.Fatalf("failed to resolve raft advertise address: %v", err)
+	}
+
+	transport, err := raft.NewTCPTransport(":"+config.Configuration.GetRaftPort(), advertise, transportPool, transportTimeout, os.Stderr)
+	if err != nil {
+		log.Fatalf("failed to start raft transport: %v", err)
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(config.Configuration.GetIdAsString())
+
+	fsm := newFSM()
+	r, err := raft.NewRaft(raftConfig, fsm, store, store, snapshots, transport)
+	if err != nil {
+		log.Fatalf("failed to start raft: %v", err)
+	}
+
+	hasState, err := raft.HasExistingState(store, store, snapshots)
+	if err != nil {
+		log.Fatalf("failed to check existing raft state: %v", err)
+	}
+
+	if !hasState {
+		bootstrap(r)
+	}
+
+	Replica = &Node{
+		raft: r,
+		fsm:  fsm,
+	}
+}
+
+// every tower bootstraps with the same configuration, so it does not matter
+// which one does it first
+func bootstrap(r *raft.Raft) {
+	peers := config.Configuration.GetRaftPeers()
+	servers := make([]raft.Server, 0, len(peers)+1)
+	hasSelf := false
+	for _, peer := range peers {
+		hasSelf = hasSelf || peer == config.Configuration.GetId()
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(peer.String()),
+			Address: raft.ServerAddress(peerAddress(peer)),
+		})
+	}
+
+	if !hasSelf {
+		id := config.Configuration.GetId()
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(id.String()),
+			Address: raft.ServerAddress(peerAddress(id)),
+		})
+	}
+
+	if err := r.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+		log.Fatalf("failed to bootstrap raft cluster: %v", err)
+	}
+}
+
+func peerAddress(id types.UUID) string {
+	return fmt.Sprintf("t-%s.tower.%s:%s", id.String(), config.Configuration.GetBaseDns(), config.Configuration.GetRaftPort())
+}
+
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+func (n *Node) LeaderCh() <-chan bool {
+	return n.raft.LeaderCh()
+}
+
+func (n *Node) Leader() (types.UUID, int64, error) {
+	term, err := strconv.ParseInt(n.raft.Stats()["term"], 10, 64)
+	if err != nil {
+		return types.UUID{}, 0, fmt.Errorf("failed to parse raft term: %w", err)
+	}
+
+	_, id := n.raft.LeaderWithID()
+	if id == "" {
+		return types.UUID{}, term, ErrNoLeader
+	}
+
+	leaderUuid, err := uuid.Parse(string(id))
+	if err != nil {
+		return types.UUID{}, 0, fmt.Errorf("failed to parse raft leader id: %w", err)
+	}
+
+	return types.UUID(leaderUuid), term, nil
+}
+
+func (n *Node) TransferLeadership() error {
+	return n.raft.LeadershipTransfer().Error()
+}
+
+func (n *Node) TransferLeadershipTo(target types.UUID) error {
+	return n.raft.LeadershipTransferToServer(raft.ServerID(target.String()), raft.ServerAddress(peerAddress(target))).Error()
+}
+
+func (n *Node) Shutdown() error {
+	return n.raft.Shutdown().Error()
+}
+
+func (n *Node) ListTowers() []types.Tower {
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	return slices.Clone(n.fsm.state.Towers)
+}
+
+func (n *Node) ListStructures() types.Structures {
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	return n.fsm.state.Structures
+}
+
+func (n *Node) ListFreeSlots(structureUuid types.UUID, slotType types.SlotType) []int {
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	free := []int{}
+	for number := 1; number <= n.fsm.slotCount(structureUuid, slotType); number++ {
+		if _, ok := n.fsm.state.Slots[slotKey(structureUuid, slotType, number)]; !ok {
+			free = append(free, number)
+		}
+	}
+
+	return free
+}
+
+func (n *Node) ListHeldSlots(structureUuid types.UUID) []types.SlotReservation {
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	held := []types.SlotReservation{}
+	prefix := structureUuid.String() + "/"
+	for key := range n.fsm.state.Slots {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if reservation, ok := n.fsm.reservation(key); ok {
+			held = append(held, reservation)
+		}
+	}
+
+	return held
+}
+
+func (n *Node) AcquireSlot(request types.AcquireSlotRequest, reservedUntil time.Time) (*types.AcquireSlotResponse, error) {
+	response, err := n.apply(command{Type: acquireSlotCommand, Slot: &request, ReservedUntil: reservedUntil.Unix()})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.AcquireSlotResponse{
+		Result: response.result,
+	}, nil
+}
+
+// PreemptSlot only takes slots reserved for a vehicle that has not arrived yet.
+func (n *Node) PreemptSlot(request types.AcquireSlotRequest, reservedUntil time.Time) (*types.AcquireSlotResponse, *types.SlotReservation, error) {
+	response, err := n.apply(command{Type: preemptSlotCommand, Slot: &request, ReservedUntil: reservedUntil.Unix()})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &types.AcquireSlotResponse{
+		Result: response.result,
+	}, response.displaced, nil
+}
+
+func (n *Node) OccupySlot(request types.OccupySlotRequest) error {
+	slot := types.AcquireSlotRequest(request)
+	_, err := n.apply(command{Type: occupySlotCommand, Slot: &slot})
+	return err
+}
+
+// ReleaseSlot holds the slot back until offeredUntil for the head of its
+// waitlist.
+func (n *Node) ReleaseSlot(request types.ReleaseSlotLockRequest, offeredUntil time.Time) error {
+	slot := types.AcquireSlotRequest(request)
+	_, err := n.apply(command{Type: releaseSlotCommand, Slot: &slot, ReservedUntil: offeredUntil.Unix()})
+	return err
+}
+
+func (n *Node) EnqueueWaitlist(entry types.WaitlistEntry) (int, error) {
+	if _, err := n.apply(command{Type: enqueueWaitlistCommand, Waitlist: &entry}); err != nil {
+		return 0, err
+	}
+
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	return n.fsm.waitlistPosition(entry.VehicleUUID), nil
+}
+
+func (n *Node) LeaveWaitlist(vehicleUuid types.UUID) error {
+	_, err := n.apply(command{Type: leaveWaitlistCommand, Vehicle: vehicleUuid})
+	return err
+}
+
+func (n *Node) OfferedTo(structureUuid types.UUID, slot types.StructureSlotRequest) (*types.WaitlistEntry, bool) {
+	n.fsm.mu.RLock()
+	defer n.fsm.mu.RUnlock()
+
+	entry, ok := n.fsm.offeredTo(slotKey(structureUuid, slot.SlotType, slot.SlotNumber))
+	return &entry, ok
+}
+
+func (n *Node) WithdrawOffer(request types.AcquireSlotRequest) error {
+	_, err := n.apply(command{Type: withdrawOfferCommand, Slot: &request})
+	return err
+}
+
+// ExpireReservations holds the freed slots back until offeredUntil for the
+// heads of their waitlists.
+func (n *Node) ExpireReservations(now time.Time, offeredUntil time.Time) ([]types.SlotReservation, error) {
+	response, err := n.apply(command{Type: expireReservationsCommand, Now: now.Unix(), ReservedUntil: offeredUntil.Unix()})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.expired, nil
+}
+
+// SyncTowers skips the log entry when the replicated state already matches.
+func (n *Node) SyncTowers(towers []types.Tower) error {
+	if reflect.DeepEqual(n.ListTowers(), towers) {
+		return nil
+	}
+
+	_, err := n.apply(command{Type: syncTowersCommand, Towers: towers})
+	return err
+}
+
+func (n *Node) SyncStructures(structures types.Structures) error {
+	if reflect.DeepEqual(n.ListStructures(), structures) {
+		return nil
+	}
+
+	_, err := n.apply(command{Type: syncStructuresCommand, Structures: &structures})
+	return err
+}
+
+func (n *Node) apply(cmd command) (applyResult, error) {
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return applyResult{}, fmt.Errorf("failed to marshal raft command: %w", err)
+	}
+
+	future := n.raft.Apply(payload, applyTimeout)
+	if err := future.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return applyResult{}, fmt.Errorf("failed to apply raft command: %w: %w", utils.ErrStaleTerm, err)
+		}
+
+		return applyResult{}, fmt.Errorf("failed to apply raft command: %w", err)
+	}
+
+	response := future.Response().(applyResult)
+	return response, response.err
+}
@@ -0,0 +1,301 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const ReplayedKey = "idempotency_replayed"
+
+const storeFile = "idempotency.json"
+
+const (
+	// claims left behind by a tower that stopped while running the request
+	ClaimTimeout = 30 * time.Second
+
+	claimWait         = 5 * time.Second
+	claimPollInterval = 100 * time.Millisecond
+)
+
+type Result struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+	RequestHash string `json:"request_hash"`
+	Pending     bool   `json:"pending,omitempty"`
+}
+
+// Store claims a key before its request runs: Claim returns the stored
+// result instead when there is one, or ErrIdempotencyKeyBusy while another
+// request holds the claim.
+type Store interface {
+	Claim(ctx context.Context, key string, requestHash string) (*Result, error)
+	Put(ctx context.Context, key string, result Result) error
+	Release(ctx context.Context, key string) error
+}
+
+func SetKeyHeader(req *http.Request, key string) {
+	if key != "" {
+		req.Header.Set(utils.IdempotencyKeyHeader, key)
+	}
+}
+
+func hashBody(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
+
+// Middleware replays the stored response of a key already served on the same
+// path, and refuses a key reused with another body. Requests are served as
+// usual when the store fails.
+func Middleware(store Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader(utils.IdempotencyKeyHeader)
+		if header == "" {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, fmt.Errorf("failed to read request body: %w", utils.ErrInvalidInput))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashBody(body)
+
+		key := strings.TrimSuffix(ctx.Request.URL.Path, "/") + ":" + header
+		result, err := claim(ctx, store, key, requestHash)
+		if result != nil && !matches(result, requestHash) {
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, fmt.Errorf("key %s was used with another request body: %w", header, utils.ErrIdempotencyKeyReused))
+			ctx.Abort()
+			return
+		}
+
+		if errors.Is(err, utils.ErrIdempotencyKeyBusy) {
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, fmt.Errorf("key %s: %w", header, err))
+			ctx.Abort()
+			return
+		}
+
+		if err != nil {
+			log.Printf("[idempotency][middleware] failed to claim key %s: %v", key, err)
+			ctx.Next()
+			return
+		}
+
+		if result != nil {
+			ctx.Set(ReplayedKey, true)
+			ctx.Header(utils.IdempotentReplayedHeader, "true")
+			ctx.Data(result.StatusCode, result.ContentType, result.Body)
+			ctx.Abort()
+			return
+		}
+
+		intercepter := &types.BodyIntercepter{Body: bytes.NewBufferString(""), ResponseWriter: ctx.Writer}
+		ctx.Writer = intercepter
+
+		ctx.Next()
+
+		// failures are not stored, so retrying them runs the request again
+		status := ctx.Writer.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			if err := store.Release(ctx, key); err != nil {
+				log.Printf("[idempotency][middleware] failed to release key %s: %v", key, err)
+			}
+			return
+		}
+
+		result = &Result{
+			StatusCode:  status,
+			ContentType: ctx.Writer.Header().Get("Content-Type"),
+			Body:        intercepter.Body.Bytes(),
+			RequestHash: requestHash,
+		}
+
+		if err := store.Put(ctx, key, *result); err != nil {
+			log.Printf("[idempotency][middleware] failed to store result of key %s: %v", key, err)
+		}
+	}
+}
+
+func claim(ctx *gin.Context, store Store, key string, requestHash string) (*Result, error) {
+	deadline := time.Now().Add(claimWait)
+	for {
+		result, err := store.Claim(ctx, key, requestHash)
+		if !errors.Is(err, utils.ErrIdempotencyKeyBusy) || (result != nil && !matches(result, requestHash)) || time.Now().After(deadline) {
+			return result, err
+		}
+
+		select {
+		case <-time.After(claimPollInterval):
+		case <-ctx.Request.Context().Done():
+			return result, err
+		}
+	}
+}
+
+// results stored before bodies were hashed match any body
+func matches(result *Result, requestHash string) bool {
+	return result.RequestHash == "" || result.RequestHash == requestHash
+}
+
+type memoryEntry struct {
+	Result   Result    `json:"result"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// MemoryStore writes its results to path, when set, so a restarted tower
+// still knows the keys it handled.
+type MemoryStore struct {
+	mu        sync.Mutex
+	retention time.Duration
+	path      string
+	results   map[string]memoryEntry
+}
+
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		results:   map[string]memoryEntry{},
+	}
+}
+
+func NewFileStore(dir string, retention time.Duration) (*MemoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency store dir: %w", err)
+	}
+
+	s := NewMemoryStore(retention)
+	s.path = filepath.Join(dir, storeFile)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.results); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency store: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Result, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entry(key)
+	if !ok || entry.Result.Pending {
+		return nil, false, nil
+	}
+
+	return &entry.Result, true, nil
+}
+
+func (s *MemoryStore) Claim(ctx context.Context, key string, requestHash string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entry(key)
+	if ok && !entry.Result.Pending {
+		return &entry.Result, nil
+	}
+
+	if ok && time.Since(entry.StoredAt) < ClaimTimeout {
+		return &entry.Result, utils.ErrIdempotencyKeyBusy
+	}
+
+	s.results[key] = memoryEntry{Result: Result{RequestHash: requestHash, Pending: true}, StoredAt: time.Now()}
+	return nil, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.results[key]; ok && entry.Result.Pending {
+		delete(s.results, key)
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) entry(key string) (memoryEntry, bool) {
+	entry, ok := s.results[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+
+	if time.Since(entry.StoredAt) > s.retention {
+		delete(s.results, key)
+		return memoryEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, result Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for stored, entry := range s.results {
+		if now.Sub(entry.StoredAt) > s.retention {
+			delete(s.results, stored)
+		}
+	}
+
+	s.results[key] = memoryEntry{Result: result, StoredAt: now}
+	return s.persist()
+}
+
+// claims are not written, since the requests holding them die with the tower
+func (s *MemoryStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	results := maps.Clone(s.results)
+	maps.DeleteFunc(results, func(_ string, entry memoryEntry) bool { return entry.Result.Pending })
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write idempotency store: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace idempotency store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const retention = time.Hour
+
+func TestMemoryStoreExpiresResults(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(retention)
+
+	if err := s.Put(ctx, "key", Result{StatusCode: http.StatusOK}); err != nil {
+		t.Fatalf("failed to put result: %v", err)
+	}
+
+	if _, found, _ := s.Get(ctx, "key"); !found {
+		t.Fatalf("result not found within the retention window")
+	}
+
+	entry := s.results["key"]
+	entry.StoredAt = entry.StoredAt.Add(-2 * retention)
+	s.results["key"] = entry
+
+	if _, found, _ := s.Get(ctx, "key"); found {
+		t.Fatalf("result found past the retention window")
+	}
+
+	if result, err := s.Claim(ctx, "key", "hash"); result != nil || err != nil {
+		t.Fatalf("claiming an expired key returned %+v, %v, want a claim", result, err)
+	}
+}
+
+func TestMemoryStoreClaims(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(retention)
+
+	if result, err := s.Claim(ctx, "key", "hash"); result != nil || err != nil {
+		t.Fatalf("first claim returned %+v, %v, want a claim", result, err)
+	}
+
+	if _, err := s.Claim(ctx, "key", "hash"); !errors.Is(err, utils.ErrIdempotencyKeyBusy) {
+		t.Fatalf("second claim returned %v, want %v", err, utils.ErrIdempotencyKeyBusy)
+	}
+
+	if _, found, _ := s.Get(ctx, "key"); found {
+		t.Fatalf("claim found as a result")
+	}
+
+	if err := s.Release(ctx, "key"); err != nil {
+		t.Fatalf("failed to release claim: %v", err)
+	}
+
+	if result, err := s.Claim(ctx, "key", "hash"); result != nil || err != nil {
+		t.Fatalf("claim after a release returned %+v, %v, want a claim", result, err)
+	}
+
+	// a claim left behind by a request that never finished is taken over
+	entry := s.results["key"]
+	entry.StoredAt = entry.StoredAt.Add(-ClaimTimeout)
+	s.results["key"] = entry
+
+	if result, err := s.Claim(ctx, "key", "hash"); result != nil || err != nil {
+		t.Fatalf("claim of a stale claim returned %+v, %v, want a claim", result, err)
+	}
+
+	if err := s.Put(ctx, "key", Result{StatusCode: http.StatusCreated, RequestHash: "hash"}); err != nil {
+		t.Fatalf("failed to put result: %v", err)
+	}
+
+	if result, err := s.Claim(ctx, "key", "hash"); err != nil || result == nil || result.StatusCode != http.StatusCreated {
+		t.Fatalf("claim of a stored key returned %+v, %v, want the stored result", result, err)
+	}
+}
+
+func TestFileStoreKeepsResultsAcrossReloads(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s, err := NewFileStore(dir, retention)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if _, err := s.Claim(ctx, "pending", "hash"); err != nil {
+		t.Fatalf("failed to claim key: %v", err)
+	}
+
+	if err := s.Put(ctx, "stored", Result{StatusCode: http.StatusOK, Body: []byte("body"), RequestHash: "hash"}); err != nil {
+		t.Fatalf("failed to put result: %v", err)
+	}
+
+	reloaded, err := NewFileStore(dir, retention)
+	if err != nil {
+		t.Fatalf("failed to reload store: %v", err)
+	}
+
+	result, found, err := reloaded.Get(ctx, "stored")
+	if err != nil || !found || string(result.Body) != "body" || result.RequestHash != "hash" {
+		t.Fatalf("reloaded result = %+v, %t, %v, want the stored result", result, found, err)
+	}
+
+	if result, err := reloaded.Claim(ctx, "pending", "hash"); result != nil || err != nil {
+		t.Fatalf("claim of a key claimed before the reload returned %+v, %v, want a claim", result, err)
+	}
+}
+
+// newRouter serves POST /slots through the middleware, answering with the
+// given status and counting the requests that ran.
+func newRouter(store Store, status *atomic.Int32, runs *atomic.Int32, hold chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/slots", Middleware(store), func(ctx *gin.Context) {
+		runs.Add(1)
+		if hold != nil {
+			<-hold
+		}
+
+		ctx.JSON(int(status.Load()), gin.H{"run": runs.Load()})
+	})
+
+	return router
+}
+
+func send(router *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/slots", strings.NewReader(body))
+	req.Header.Set(utils.IdempotencyKeyHeader, key)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		first    string
+		second   string
+		code     int
+		replayed bool
+		runs     int32
+	}{
+		{name: "retry with the same body is replayed", status: http.StatusOK, first: `{"slot":1}`, second: `{"slot":1}`, code: http.StatusOK, replayed: true, runs: 1},
+		{name: "key reused with another body is refused", status: http.StatusOK, first: `{"slot":1}`, second: `{"slot":2}`, code: http.StatusUnprocessableEntity, runs: 1},
+		{name: "retry of a failure runs again", status: http.StatusServiceUnavailable, first: `{"slot":1}`, second: `{"slot":1}`, code: http.StatusServiceUnavailable, runs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status, runs atomic.Int32
+			status.Store(int32(tt.status))
+			router := newRouter(NewMemoryStore(retention), &status, &runs, nil)
+
+			send(router, "key", tt.first)
+			response := send(router, "key", tt.second)
+
+			if response.Code != tt.code {
+				t.Errorf("second request answered %d, want %d", response.Code, tt.code)
+			}
+
+			if replayed := response.Header().Get(utils.IdempotentReplayedHeader) == "true"; replayed != tt.replayed {
+				t.Errorf("second request replayed: %t, want %t", replayed, tt.replayed)
+			}
+
+			if runs.Load() != tt.runs {
+				t.Errorf("handler ran %d times, want %d", runs.Load(), tt.runs)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRunsConcurrentRetriesOnce(t *testing.T) {
+	var status, runs atomic.Int32
+	status.Store(http.StatusOK)
+	hold := make(chan struct{})
+	router := newRouter(NewMemoryStore(retention), &status, &runs, hold)
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 2)
+	wg.Go(func() { responses[0] = send(router, "key", `{"slot":1}`) })
+
+	for runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	wg.Go(func() { responses[1] = send(router, "key", `{"slot":1}`) })
+
+	// the retry waits on the claim while the first request runs
+	time.Sleep(3 * claimPollInterval)
+	close(hold)
+	wg.Wait()
+
+	if runs.Load() != 1 {
+		t.Fatalf("handler ran %d times for concurrent retries, want 1", runs.Load())
+	}
+
+	for i, response := range responses {
+		if response.Code != http.StatusOK || response.Body.String() != `{"run":1}` {
+			t.Errorf("request %d answered %d %s, want the response of the first run", i, response.Code, response.Body.String())
+		}
+	}
+
+	if responses[1].Header().Get(utils.IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry was not replayed")
+	}
+}
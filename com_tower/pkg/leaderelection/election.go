@@ -2,26 +2,110 @@ package leaderelection
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 )
 
-var ChangeRoleCh = make(chan types.Role)
+// LockElector confirms the winner of the bully election by taking the tower
+// lock.
+type LockElector struct {
+	roleNotifier
+	campaigning sync.Mutex
+}
+
+func NewLockElector() *LockElector {
+	return &LockElector{
+		roleNotifier: newRoleNotifier(),
+	}
+}
+
+func (e *LockElector) Campaign(ctx context.Context, towers []types.Tower) (bool, error) {
+	if !e.campaigning.TryLock() {
+		log.Printf("[minion][election] election already in progress, skipping")
+		return false, nil
+	}
+	defer e.campaigning.Unlock()
+
+	if isLeaderReachableByMajority(ctx, towers) {
+		log.Printf("[minion][election] leader %s is still reachable by a majority of towers: skipping election", config.Configuration.GetLeaderUUIDAsString())
+		return false, nil
+	}
+
+	if !outranksTowers(towers) {
+		e.publish(types.Minion)
+		return false, nil
+	}
 
-func StartElection(towers []types.Tower) {
-    uptime := config.Configuration.GetUptimeSeconds()
-    log.Printf("[minion][election] starting leader election: my uptime: %.2fs", uptime)
+	term, err := TryAcquireLock(ctx)
+	if errors.Is(err, ErrLockHeld) {
+		log.Printf("[minion][election] election won but tower lock is held by another leader")
+		e.publish(types.Minion)
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire tower lock: %w", err)
+	}
+
+	log.Printf("[minion][election] election won, becoming leader for term %d", term)
+	e.publish(types.Leader)
+	go AnnounceLeader(towers, config.Configuration.GetId(), term)
+	return true, nil
+}
+
+func (e *LockElector) Acquire(ctx context.Context) (int64, error) {
+	term, err := TryAcquireLock(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := MarkLeader(ctx); err != nil {
+		return 0, err
+	}
+
+	return term, nil
+}
+
+func (e *LockElector) Renew(ctx context.Context, term int64) error {
+	return RenewLock(ctx, term)
+}
+
+func (e *LockElector) Release(ctx context.Context, term int64) error {
+	return ReleaseLock(ctx, term)
+}
+
+func (e *LockElector) Resign(ctx context.Context) error {
+	err := e.Release(ctx, config.Configuration.GetLeaderTerm())
+	e.publish(types.Minion)
+	return err
+}
+
+func (e *LockElector) Leader(ctx context.Context) (types.UUID, int64, error) {
+	return GetCurrentLeader(ctx)
+}
+
+// the target still has to take over the leader role
+func (e *LockElector) Transfer(ctx context.Context, target types.UUID) (int64, error) {
+	return TransferLock(ctx, target, config.Configuration.GetLeaderTerm())
+}
+
+func outranksTowers(towers []types.Tower) bool {
+    candidate := config.Configuration.GetCandidate()
+    log.Printf("[minion][election] starting leader election: my priority: %d, started at: %d", candidate.Priority, candidate.StartedAt)
 
     electionReq := types.ElectionRequest{
-        CandidateUptime: uptime,
+        Candidate: candidate,
     }
 
-    hasHighestUptime := true
+    outranksAll := true
     for _, tower := range towers {
         if config.Configuration.GetId() == tower.UUID {
             continue
@@ -31,7 +115,7 @@ func StartElection(towers []types.Tower) {
         payload, err := json.Marshal(electionReq)
 		if err != nil {
 			log.Printf("[minion][election] failed to marshal election request: %v", err)
-			return
+			return false
 		}
         
         resp, err := http.Post(url, "application/json", bytes.NewBuffer(payload))
@@ -48,27 +132,24 @@ func StartElection(towers []types.Tower) {
             continue
         }
 
-        if resp.StatusCode == http.StatusOK && electionResp.HasHigherUptime {
-            log.Printf("[minion][election] tower %s has a higher uptime of (%.2fs): stopping election", tower.UUID.String(), electionResp.Uptime)
-            hasHighestUptime = false
+        if resp.StatusCode == http.StatusOK && electionResp.Outranks {
+            log.Printf("[minion][election] tower %s outranks me with priority %d, started at %d: stopping election", tower.UUID.String(), electionResp.Tower.Priority, electionResp.Tower.StartedAt)
+            outranksAll = false
             break
         }
     }
     
-    if !hasHighestUptime {
-		log.Printf("[minion][election] election lost, delegating election to another tower with a higher uptime")
-		return
+    if !outranksAll {
+		log.Printf("[minion][election] election lost, delegating election to a higher ranked tower")
     }
-    
-	log.Printf("[minion][election] election won, becoming leader")
-	config.Configuration.SetLeaderUUID(config.Configuration.GetId())
-	ChangeRoleCh <- types.Leader
-	go broadcastCoordinator(towers)
+
+	return outranksAll
 }
 
-func broadcastCoordinator(towers []types.Tower) {
+func AnnounceLeader(towers []types.Tower, leaderUuid types.UUID, term int64) {
     coordinatorReq := types.NewLeaderRequest{
-        NewLeaderUUID: config.Configuration.GetId(),
+        NewLeaderUUID: leaderUuid,
+        Term:          term,
     }
 
     payload, err := json.Marshal(coordinatorReq)
@@ -0,0 +1,49 @@
+package leaderelection
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// Elector tells the tower through Leadership which role to assume.
+type Elector interface {
+	Campaign(ctx context.Context, towers []types.Tower) (bool, error)
+	Acquire(ctx context.Context) (int64, error)
+	// Renew fails with utils.ErrLockLost once the leadership is lost.
+	Renew(ctx context.Context, term int64) error
+	Release(ctx context.Context, term int64) error
+	Resign(ctx context.Context) error
+	Leader(ctx context.Context) (types.UUID, int64, error)
+	Transfer(ctx context.Context, target types.UUID) (int64, error)
+	// only the latest role is kept, so slow readers never block the backend
+	Leadership() <-chan types.Role
+}
+
+type roleNotifier struct {
+	mu sync.Mutex
+	ch chan types.Role
+}
+
+func newRoleNotifier() roleNotifier {
+	return roleNotifier{
+		ch: make(chan types.Role, 1),
+	}
+}
+
+func (n *roleNotifier) publish(role types.Role) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	select {
+	case <-n.ch:
+	default:
+	}
+
+	n.ch <- role
+}
+
+func (n *roleNotifier) Leadership() <-chan types.Role {
+	return n.ch
+}
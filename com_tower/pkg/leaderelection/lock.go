@@ -2,37 +2,99 @@ package leaderelection
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
-	AcquireLockQuery = "UPDATE tower_lock SET leader_id = $1, renewed_at = NOW() WHERE leader_id IS NULL;"
-	GetLeaderQuery   = "SELECT leader_id FROM tower_lock LIMIT 1;"
+	AcquireLockQuery  = "UPDATE tower_lock SET leader_id = $1, renewed_at = NOW(), term = CASE WHEN leader_id = $1 THEN term ELSE term + 1 END WHERE leader_id = $1 OR leader_id IS NULL OR renewed_at < (NOW() - ($2 || ' seconds')::interval) RETURNING term;"
+	RenewLockQuery    = "UPDATE tower_lock SET renewed_at = NOW() WHERE leader_id = $1 AND term = $2;"
+	ReleaseLockQuery  = "UPDATE tower_lock SET leader_id = NULL WHERE leader_id = $1 AND term = $2;"
+	MarkLeaderQuery   = "UPDATE towers SET is_leader = (id = $1);"
+	GetLeaderQuery    = "SELECT leader_id, term FROM tower_lock LIMIT 1;"
+	TransferLockQuery = "UPDATE tower_lock SET leader_id = $2, renewed_at = NOW(), term = term + 1 WHERE leader_id = $1 AND term = $3 AND EXISTS (SELECT 1 FROM towers WHERE id = $2) RETURNING term;"
 )
 
-func AcquireLockIfEmptyAndReturnLeaderUUID(ctx context.Context) types.UUID {
-	tag, err := config.Configuration.GetDBPool().Exec(ctx, AcquireLockQuery, config.Configuration.GetId())
+var (
+	ErrLockHeld = errors.New("tower lock is held by another leader")
+	ErrNoLeader = errors.New("no leader holds the tower lock")
+)
+
+func TryAcquireLock(ctx context.Context) (int64, error) {
+	var term int64
+	timeout := strconv.Itoa(int(config.Configuration.GetRenewLockTimeout().Seconds()))
+	err := config.Configuration.GetDBPool().QueryRow(ctx, AcquireLockQuery, config.Configuration.GetIdAsString(), timeout).Scan(&term)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrLockHeld
+	}
+
+	return term, err
+}
+
+func MarkLeader(ctx context.Context) error {
+	tag, err := config.Configuration.GetDBPool().Exec(ctx, MarkLeaderQuery, config.Configuration.GetIdAsString())
 	if err != nil {
-		log.Fatalf("failed to ensure leader lock: %v", err)
+		return err
 	}
 
 	if tag.RowsAffected() == 0 {
-		var id string
-		if err := config.Configuration.GetDBPool().QueryRow(ctx, GetLeaderQuery).Scan(&id); err != nil {
-			log.Fatalf("failed to query leader uuid: %v", err)
-		}
+		return errors.New("failed to set other towers as non leaders")
+	}
 
-		uuid, err := uuid.Parse(id)
-		if err != nil {
-			log.Fatalf("failed to parse leader uuid: %v", err)
-		}
+	return nil
+}
+
+func RenewLock(ctx context.Context, term int64) error {
+	tag, err := config.Configuration.GetDBPool().Exec(ctx, RenewLockQuery, config.Configuration.GetIdAsString(), term)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("no rows affected, lock was not renewed: %w", utils.ErrLockLost)
+	}
 
-		return types.UUID(uuid)
+	return nil
+}
+
+func ReleaseLock(ctx context.Context, term int64) error {
+	_, err := config.Configuration.GetDBPool().Exec(ctx, ReleaseLockQuery, config.Configuration.GetIdAsString(), term)
+	return err
+}
+
+// TransferLock never hands the lock to a tower missing from the towers table.
+func TransferLock(ctx context.Context, target types.UUID, term int64) (int64, error) {
+	var newTerm int64
+	err := config.Configuration.GetDBPool().QueryRow(ctx, TransferLockQuery, config.Configuration.GetIdAsString(), target.String(), term).Scan(&newTerm)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrLockHeld
+	}
+
+	return newTerm, err
+}
+
+func GetCurrentLeader(ctx context.Context) (types.UUID, int64, error) {
+	var id *string
+	var term int64
+	if err := config.Configuration.GetDBPool().QueryRow(ctx, GetLeaderQuery).Scan(&id, &term); err != nil {
+		return types.UUID{}, 0, err
+	}
+
+	if id == nil {
+		return types.UUID{}, term, ErrNoLeader
+	}
+
+	leaderUuid, err := uuid.Parse(*id)
+	if err != nil {
+		return types.UUID{}, 0, err
 	}
 
-	return config.Configuration.GetId()
+	return types.UUID(leaderUuid), term, nil
 }
@@ -0,0 +1,124 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+)
+
+// MemoryCluster lets several towers run their elections in a single process.
+type MemoryCluster struct {
+	mu     sync.Mutex
+	leader *types.UUID
+	term   int64
+}
+
+func NewMemoryCluster() *MemoryCluster {
+	return &MemoryCluster{}
+}
+
+type MemoryElector struct {
+	roleNotifier
+	id      types.UUID
+	cluster *MemoryCluster
+}
+
+func NewMemoryElector(cluster *MemoryCluster, id types.UUID) *MemoryElector {
+	return &MemoryElector{
+		roleNotifier: newRoleNotifier(),
+		id:           id,
+		cluster:      cluster,
+	}
+}
+
+func (e *MemoryElector) Campaign(ctx context.Context, towers []types.Tower) (bool, error) {
+	_, err := e.Acquire(ctx)
+	if errors.Is(err, ErrLockHeld) {
+		e.publish(types.Minion)
+		return false, nil
+	}
+
+	e.publish(types.Leader)
+	return true, nil
+}
+
+func (e *MemoryElector) Acquire(ctx context.Context) (int64, error) {
+	e.cluster.mu.Lock()
+	defer e.cluster.mu.Unlock()
+
+	if e.cluster.leader == nil {
+		e.cluster.leader = &e.id
+		e.cluster.term++
+	}
+
+	if *e.cluster.leader != e.id {
+		return 0, ErrLockHeld
+	}
+
+	return e.cluster.term, nil
+}
+
+func (e *MemoryElector) Renew(ctx context.Context, term int64) error {
+	e.cluster.mu.Lock()
+	defer e.cluster.mu.Unlock()
+
+	if !e.holds(term) {
+		return fmt.Errorf("leadership of term %d is not held: %w", term, utils.ErrLockLost)
+	}
+
+	return nil
+}
+
+func (e *MemoryElector) Release(ctx context.Context, term int64) error {
+	e.cluster.mu.Lock()
+	defer e.cluster.mu.Unlock()
+
+	if e.holds(term) {
+		e.cluster.leader = nil
+	}
+
+	return nil
+}
+
+func (e *MemoryElector) Resign(ctx context.Context) error {
+	e.cluster.mu.Lock()
+	if e.cluster.leader != nil && *e.cluster.leader == e.id {
+		e.cluster.leader = nil
+	}
+	e.cluster.mu.Unlock()
+
+	e.publish(types.Minion)
+	return nil
+}
+
+func (e *MemoryElector) holds(term int64) bool {
+	return e.cluster.leader != nil && *e.cluster.leader == e.id && e.cluster.term == term
+}
+
+func (e *MemoryElector) Leader(ctx context.Context) (types.UUID, int64, error) {
+	e.cluster.mu.Lock()
+	defer e.cluster.mu.Unlock()
+
+	if e.cluster.leader == nil {
+		return types.UUID{}, e.cluster.term, ErrNoLeader
+	}
+
+	return *e.cluster.leader, e.cluster.term, nil
+}
+
+func (e *MemoryElector) Transfer(ctx context.Context, target types.UUID) (int64, error) {
+	e.cluster.mu.Lock()
+	defer e.cluster.mu.Unlock()
+
+	if e.cluster.leader == nil || *e.cluster.leader != e.id {
+		return 0, ErrLockHeld
+	}
+
+	e.cluster.leader = &target
+	e.cluster.term++
+	return e.cluster.term, nil
+}
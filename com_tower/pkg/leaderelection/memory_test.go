@@ -0,0 +1,191 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/google/uuid"
+)
+
+func newMemoryTowers(cluster *MemoryCluster, count int) []*MemoryElector {
+	electors := make([]*MemoryElector, 0, count)
+	for range count {
+		electors = append(electors, NewMemoryElector(cluster, types.UUID(uuid.New())))
+	}
+
+	return electors
+}
+
+func latestRole(t *testing.T, elector *MemoryElector) types.Role {
+	t.Helper()
+
+	select {
+	case role := <-elector.Leadership():
+		return role
+	default:
+		t.Fatalf("tower %s was not told which role to assume", elector.id.String())
+		return types.Minion
+	}
+}
+
+func TestMemoryElectorElectsSingleLeader(t *testing.T) {
+	ctx := context.Background()
+	electors := newMemoryTowers(NewMemoryCluster(), 5)
+
+	won := make([]bool, len(electors))
+	var wg sync.WaitGroup
+	for i, elector := range electors {
+		wg.Go(func() {
+			var err error
+			won[i], err = elector.Campaign(ctx, nil)
+			if err != nil {
+				t.Errorf("campaign failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	var leader *MemoryElector
+	for i, elector := range electors {
+		role := latestRole(t, elector)
+		if won[i] != (role == types.Leader) {
+			t.Fatalf("tower %s won %t but was told to assume the %s role", elector.id.String(), won[i], role)
+		}
+
+		if won[i] {
+			if leader != nil {
+				t.Fatalf("towers %s and %s both won the election", leader.id.String(), elector.id.String())
+			}
+			leader = elector
+		}
+	}
+
+	if leader == nil {
+		t.Fatal("no tower won the election")
+	}
+
+	for _, elector := range electors {
+		leaderUuid, term, err := elector.Leader(ctx)
+		if err != nil {
+			t.Fatalf("failed to get leader: %v", err)
+		}
+
+		if leaderUuid != leader.id || term != 1 {
+			t.Fatalf("tower %s sees leader %s in term %d, want %s in term 1", elector.id.String(), leaderUuid.String(), term, leader.id.String())
+		}
+	}
+}
+
+func TestMemoryElectorRenewsOnlyHeldTerm(t *testing.T) {
+	ctx := context.Background()
+	electors := newMemoryTowers(NewMemoryCluster(), 2)
+	leader, follower := electors[0], electors[1]
+
+	term, err := leader.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("failed to acquire leadership: %v", err)
+	}
+
+	if _, err := follower.Acquire(ctx); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("follower acquired held leadership: %v", err)
+	}
+
+	if err := leader.Renew(ctx, term); err != nil {
+		t.Fatalf("failed to renew held leadership: %v", err)
+	}
+
+	if err := leader.Renew(ctx, term-1); !errors.Is(err, utils.ErrLockLost) {
+		t.Fatalf("renewed leadership of another term: %v", err)
+	}
+
+	if err := follower.Renew(ctx, term); !errors.Is(err, utils.ErrLockLost) {
+		t.Fatalf("follower renewed leadership it does not hold: %v", err)
+	}
+
+	if err := follower.Release(ctx, term); err != nil {
+		t.Fatalf("failed to release leadership not held: %v", err)
+	}
+
+	if err := leader.Renew(ctx, term); err != nil {
+		t.Fatalf("leadership was released by the follower: %v", err)
+	}
+}
+
+func TestMemoryElectorResignReleasesLeadership(t *testing.T) {
+	ctx := context.Background()
+	electors := newMemoryTowers(NewMemoryCluster(), 2)
+	leader, follower := electors[0], electors[1]
+
+	if won, _ := leader.Campaign(ctx, nil); !won {
+		t.Fatal("first tower lost an uncontested election")
+	}
+
+	if won, _ := follower.Campaign(ctx, nil); won {
+		t.Fatal("second tower won the election while leadership was held")
+	}
+
+	if err := leader.Resign(ctx); err != nil {
+		t.Fatalf("failed to resign: %v", err)
+	}
+
+	if role := latestRole(t, leader); role != types.Minion {
+		t.Fatalf("resigned tower was told to assume the %s role", role)
+	}
+
+	if _, _, err := leader.Leader(ctx); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("leadership is still held after resigning: %v", err)
+	}
+
+	if won, _ := follower.Campaign(ctx, nil); !won {
+		t.Fatal("second tower lost the election after the leader resigned")
+	}
+
+	if _, term, _ := follower.Leader(ctx); term != 2 {
+		t.Fatalf("new leader leads term %d, want 2", term)
+	}
+}
+
+func TestMemoryElectorTransferHandsOverLeadership(t *testing.T) {
+	ctx := context.Background()
+	electors := newMemoryTowers(NewMemoryCluster(), 3)
+	leader, target, other := electors[0], electors[1], electors[2]
+
+	term, err := leader.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("failed to acquire leadership: %v", err)
+	}
+
+	if _, err := other.Transfer(ctx, target.id); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("tower transferred leadership it does not hold: %v", err)
+	}
+
+	newTerm, err := leader.Transfer(ctx, target.id)
+	if err != nil {
+		t.Fatalf("failed to transfer leadership: %v", err)
+	}
+
+	if newTerm != term+1 {
+		t.Fatalf("target leads term %d, want %d", newTerm, term+1)
+	}
+
+	if err := leader.Renew(ctx, term); !errors.Is(err, utils.ErrLockLost) {
+		t.Fatalf("previous leader renewed leadership after transferring it: %v", err)
+	}
+
+	if err := leader.Resign(ctx); err != nil {
+		t.Fatalf("failed to resign: %v", err)
+	}
+
+	acquired, err := target.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("target failed to take over leadership: %v", err)
+	}
+
+	if acquired != newTerm {
+		t.Fatalf("target took over term %d, want %d", acquired, newTerm)
+	}
+}
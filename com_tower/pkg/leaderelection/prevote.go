@@ -0,0 +1,87 @@
+package leaderelection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+)
+
+const preVoteTimeout = 2 * time.Second
+
+var preVoteClient = &http.Client{Timeout: preVoteTimeout}
+
+// a leader only this tower cannot reach is left alone, since campaigning would
+// just disturb the cluster
+func isLeaderReachableByMajority(ctx context.Context, towers []types.Tower) bool {
+	payload, err := json.Marshal(types.PreVoteRequest{
+		LeaderUUID: config.Configuration.GetLeaderUUID(),
+		Term:       config.Configuration.GetLeaderTerm(),
+	})
+	if err != nil {
+		log.Printf("[minion][election][pre_vote] failed to marshal pre-vote request: %v", err)
+		return false
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	voters, reachable := 1, 0
+	for _, tower := range towers {
+		if config.Configuration.GetId() == tower.UUID {
+			continue
+		}
+
+		voters++
+		wg.Go(func() {
+			resp, err := sendPreVote(ctx, tower, payload)
+			if err != nil {
+				log.Printf("[minion][election][pre_vote] failed to get pre-vote from tower %s: %v", tower.UUID.String(), err)
+				return
+			}
+
+			if resp.LeaderReachable {
+				mu.Lock()
+				reachable++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	log.Printf("[minion][election][pre_vote] %d of %d towers reach the leader", reachable, voters)
+	return reachable > voters/2
+}
+
+func sendPreVote(ctx context.Context, tower types.Tower, payload []byte) (*types.PreVoteResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/pre-vote", tower.UUID.String(), config.Configuration.GetBaseDns())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pre-vote request: %w", err)
+	}
+
+	resp, err := preVoteClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute pre-vote request: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+
+	var preVoteResp types.PreVoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&preVoteResp); err != nil {
+		return nil, fmt.Errorf("failed to decode pre-vote response: %w", err)
+	}
+
+	return &preVoteResp, nil
+}
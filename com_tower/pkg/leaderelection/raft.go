@@ -0,0 +1,98 @@
+package leaderelection
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// RaftElector only reports the outcome of the elections raft runs itself.
+type RaftElector struct {
+	roleNotifier
+	mu      sync.Mutex
+	replica *consensus.Node
+}
+
+func NewRaftElector(replica *consensus.Node) *RaftElector {
+	elector := &RaftElector{
+		roleNotifier: newRoleNotifier(),
+		replica:      replica,
+	}
+
+	go elector.observe()
+	return elector
+}
+
+func (e *RaftElector) observe() {
+	for range e.replica.LeaderCh() {
+		e.publishState()
+	}
+}
+
+// concurrent transitions always end with the latest state published
+func (e *RaftElector) publishState() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	isLeader := e.replica.IsLeader()
+	if isLeader {
+		e.publish(types.Leader)
+	} else {
+		e.publish(types.Minion)
+	}
+
+	return isLeader
+}
+
+func (e *RaftElector) Campaign(ctx context.Context, towers []types.Tower) (bool, error) {
+	return e.publishState(), nil
+}
+
+func (e *RaftElector) Acquire(ctx context.Context) (int64, error) {
+	_, term, err := e.replica.Leader()
+	return term, err
+}
+
+// Renew does nothing: raft keeps its leadership alive on its own.
+func (e *RaftElector) Renew(ctx context.Context, term int64) error {
+	return nil
+}
+
+// Release does nothing: raft leadership is only given up by Resign.
+func (e *RaftElector) Release(ctx context.Context, term int64) error {
+	return nil
+}
+
+func (e *RaftElector) Resign(ctx context.Context) error {
+	if !e.replica.IsLeader() {
+		e.publishState()
+		return nil
+	}
+
+	return e.replica.TransferLeadership()
+}
+
+func (e *RaftElector) Leader(ctx context.Context) (types.UUID, int64, error) {
+	leaderUuid, term, err := e.replica.Leader()
+	if errors.Is(err, consensus.ErrNoLeader) {
+		return types.UUID{}, term, ErrNoLeader
+	}
+
+	return leaderUuid, term, err
+}
+
+func (e *RaftElector) Transfer(ctx context.Context, target types.UUID) (int64, error) {
+	if err := e.replica.TransferLeadershipTo(target); err != nil {
+		return 0, err
+	}
+
+	_, term, err := e.replica.Leader()
+	if err != nil && !errors.Is(err, consensus.ErrNoLeader) {
+		return 0, err
+	}
+
+	return term, nil
+}
@@ -0,0 +1,171 @@
+package leader
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+const defaultAnalyticsRange = 7 * 24 * time.Hour
+
+var analyticsSlotTypes = []types.SlotType{types.DockSlotType, types.HelipadSlotType}
+
+// visits that did not end yet run until now
+func overlap(start time.Time, end *time.Time, from time.Time, to time.Time) float64 {
+	stop := time.Now()
+	if end != nil {
+		stop = *end
+	}
+
+	if start.Before(from) {
+		start = from
+	}
+
+	if stop.After(to) {
+		stop = to
+	}
+
+	if !stop.After(start) {
+		return 0
+	}
+
+	return stop.Sub(start).Seconds()
+}
+
+func utilization(visits []types.SlotVisit, slots map[types.SlotType]int, from time.Time, to time.Time) []types.SlotUtilization {
+	available := to.Sub(from).Seconds()
+	result := make([]types.SlotUtilization, 0, len(analyticsSlotTypes))
+	for _, slotType := range analyticsSlotTypes {
+		usage := types.SlotUtilization{SlotType: slotType, Slots: slots[slotType]}
+		for _, visit := range visits {
+			if visit.SlotType != slotType {
+				continue
+			}
+
+			usage.Visits++
+			usage.HeldSeconds += overlap(visit.AcquiredAt, visit.EndedAt, from, to)
+			if visit.ArrivedAt != nil {
+				usage.OccupiedSeconds += overlap(*visit.ArrivedAt, visit.EndedAt, from, to)
+			}
+		}
+
+		if usage.Slots > 0 && available > 0 {
+			usage.HeldRatio = usage.HeldSeconds / (float64(usage.Slots) * available)
+			usage.OccupiedRatio = usage.OccupiedSeconds / (float64(usage.Slots) * available)
+		}
+
+		result = append(result, usage)
+	}
+
+	return result
+}
+
+func endedBetween(visit types.SlotVisit, from time.Time, to time.Time) bool {
+	return visit.EndedAt != nil && !visit.EndedAt.Before(from) && visit.EndedAt.Before(to)
+}
+
+// dwell times run from the arrival to the departure
+func dwellTimes(visits []types.SlotVisit, from time.Time, to time.Time) map[types.SlotType][]float64 {
+	durations := make(map[types.SlotType][]float64)
+	for _, visit := range visits {
+		if visit.ArrivedAt == nil || visit.EndedBy == nil || *visit.EndedBy != types.DepartedSlotTransition || !endedBetween(visit, from, to) {
+			continue
+		}
+
+		durations[visit.SlotType] = append(durations[visit.SlotType], visit.EndedAt.Sub(*visit.ArrivedAt).Seconds())
+	}
+
+	return durations
+}
+
+// turnaround times run from the acquisition until the slot was free again
+func turnaroundTimes(visits []types.SlotVisit, from time.Time, to time.Time) map[types.SlotType][]float64 {
+	durations := make(map[types.SlotType][]float64)
+	for _, visit := range visits {
+		if !endedBetween(visit, from, to) {
+			continue
+		}
+
+		durations[visit.SlotType] = append(durations[visit.SlotType], visit.EndedAt.Sub(visit.AcquiredAt).Seconds())
+	}
+
+	return durations
+}
+
+func durationStats(durations map[types.SlotType][]float64) []types.DurationStats {
+	result := make([]types.DurationStats, 0, len(analyticsSlotTypes))
+	for _, slotType := range analyticsSlotTypes {
+		values := slices.Sorted(slices.Values(durations[slotType]))
+		stats := types.DurationStats{SlotType: slotType, Count: len(values)}
+		if len(values) > 0 {
+			var sum float64
+			for _, value := range values {
+				sum += value
+			}
+
+			stats.MeanSeconds = sum / float64(len(values))
+			stats.P50Seconds = percentile(values, 50)
+			stats.P90Seconds = percentile(values, 90)
+			stats.P95Seconds = percentile(values, 95)
+			stats.P99Seconds = percentile(values, 99)
+			stats.MaxSeconds = values[len(values)-1]
+		}
+
+		result = append(result, stats)
+	}
+
+	return result
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+func utilizationRecords(response types.UtilizationResponse) [][]string {
+	records := [][]string{{"structure_uuid", "from", "to", "slot_type", "slots", "visits", "held_seconds", "occupied_seconds", "held_ratio", "occupied_ratio"}}
+	for _, usage := range response.Utilization {
+		records = append(records, []string{
+			response.StructureUUID.String(),
+			response.From.Format(time.RFC3339),
+			response.To.Format(time.RFC3339),
+			string(usage.SlotType),
+			strconv.Itoa(usage.Slots),
+			strconv.Itoa(usage.Visits),
+			formatSeconds(usage.HeldSeconds),
+			formatSeconds(usage.OccupiedSeconds),
+			strconv.FormatFloat(usage.HeldRatio, 'f', 4, 64),
+			strconv.FormatFloat(usage.OccupiedRatio, 'f', 4, 64),
+		})
+	}
+
+	return records
+}
+
+func durationStatsRecords(response types.DurationStatsResponse) [][]string {
+	records := [][]string{{"structure_uuid", "from", "to", "slot_type", "count", "mean_seconds", "p50_seconds", "p90_seconds", "p95_seconds", "p99_seconds", "max_seconds"}}
+	for _, stats := range response.Stats {
+		records = append(records, []string{
+			response.StructureUUID.String(),
+			response.From.Format(time.RFC3339),
+			response.To.Format(time.RFC3339),
+			string(stats.SlotType),
+			strconv.Itoa(stats.Count),
+			formatSeconds(stats.MeanSeconds),
+			formatSeconds(stats.P50Seconds),
+			formatSeconds(stats.P90Seconds),
+			formatSeconds(stats.P95Seconds),
+			formatSeconds(stats.P99Seconds),
+			formatSeconds(stats.MaxSeconds),
+		})
+	}
+
+	return records
+}
@@ -0,0 +1,224 @@
+package leader
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// the window is in the past, so visits that did not end yet run past it
+var (
+	windowFrom = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	windowTo   = windowFrom.Add(10 * time.Hour)
+)
+
+func at(offset time.Duration) *time.Time {
+	t := windowFrom.Add(offset)
+	return &t
+}
+
+func endedBy(transition types.SlotTransition) *types.SlotTransition {
+	return &transition
+}
+
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Duration
+		end   *time.Time
+		want  float64
+	}{
+		{name: "inside the window", start: time.Hour, end: at(2 * time.Hour), want: 3600},
+		{name: "straddling from", start: -time.Hour, end: at(time.Hour), want: 3600},
+		{name: "straddling to", start: 9 * time.Hour, end: at(11 * time.Hour), want: 3600},
+		{name: "straddling from and to", start: -time.Hour, end: at(11 * time.Hour), want: 36000},
+		{name: "open visit", start: 9 * time.Hour, end: nil, want: 3600},
+		{name: "before the window", start: -2 * time.Hour, end: at(-time.Hour), want: 0},
+		{name: "after the window", start: 11 * time.Hour, end: at(12 * time.Hour), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlap(*at(tt.start), tt.end, windowFrom, windowTo); got != tt.want {
+				t.Fatalf("overlap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUtilization(t *testing.T) {
+	tests := []struct {
+		name   string
+		visits []types.SlotVisit
+		slots  map[types.SlotType]int
+		want   []types.SlotUtilization
+	}{
+		{
+			name: "visit straddling from and to",
+			visits: []types.SlotVisit{
+				{SlotType: types.DockSlotType, AcquiredAt: *at(-time.Hour), ArrivedAt: at(time.Hour), EndedAt: at(11 * time.Hour)},
+			},
+			slots: map[types.SlotType]int{types.DockSlotType: 2},
+			want: []types.SlotUtilization{
+				{SlotType: types.DockSlotType, Slots: 2, Visits: 1, HeldSeconds: 36000, OccupiedSeconds: 32400, HeldRatio: 0.5, OccupiedRatio: 0.45},
+				{SlotType: types.HelipadSlotType},
+			},
+		},
+		{
+			name: "open visit",
+			visits: []types.SlotVisit{
+				{SlotType: types.DockSlotType, AcquiredAt: *at(8 * time.Hour), ArrivedAt: at(9 * time.Hour)},
+				{SlotType: types.DockSlotType, AcquiredAt: *at(9 * time.Hour)},
+			},
+			slots: map[types.SlotType]int{types.DockSlotType: 2},
+			want: []types.SlotUtilization{
+				{SlotType: types.DockSlotType, Slots: 2, Visits: 2, HeldSeconds: 10800, OccupiedSeconds: 3600, HeldRatio: 0.15, OccupiedRatio: 0.05},
+				{SlotType: types.HelipadSlotType},
+			},
+		},
+		{
+			name: "slot type without slots",
+			visits: []types.SlotVisit{
+				{SlotType: types.HelipadSlotType, AcquiredAt: *at(0), EndedAt: at(time.Hour)},
+			},
+			slots: map[types.SlotType]int{types.DockSlotType: 2},
+			want: []types.SlotUtilization{
+				{SlotType: types.DockSlotType, Slots: 2},
+				{SlotType: types.HelipadSlotType, Visits: 1, HeldSeconds: 3600},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utilization(tt.visits, tt.slots, windowFrom, windowTo); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("utilization() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDwellTimes(t *testing.T) {
+	tests := []struct {
+		name  string
+		visit types.SlotVisit
+		want  []float64
+	}{
+		{
+			name:  "departed inside the window",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(0), ArrivedAt: at(time.Hour), EndedAt: at(3 * time.Hour), EndedBy: endedBy(types.DepartedSlotTransition)},
+			want:  []float64{7200},
+		},
+		{
+			name:  "arrived before from",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(-2 * time.Hour), ArrivedAt: at(-time.Hour), EndedAt: at(time.Hour), EndedBy: endedBy(types.DepartedSlotTransition)},
+			want:  []float64{7200},
+		},
+		{
+			name:  "departed after to",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(9 * time.Hour), ArrivedAt: at(9 * time.Hour), EndedAt: at(11 * time.Hour), EndedBy: endedBy(types.DepartedSlotTransition)},
+		},
+		{
+			name:  "departed at to",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(9 * time.Hour), ArrivedAt: at(9 * time.Hour), EndedAt: at(10 * time.Hour), EndedBy: endedBy(types.DepartedSlotTransition)},
+		},
+		{
+			name:  "open visit",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(0), ArrivedAt: at(time.Hour)},
+		},
+		{
+			name:  "released without arriving",
+			visit: types.SlotVisit{SlotType: types.DockSlotType, AcquiredAt: *at(0), EndedAt: at(time.Hour), EndedBy: endedBy(types.ReleasedSlotTransition)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dwellTimes([]types.SlotVisit{tt.visit}, windowFrom, windowTo)
+			if !reflect.DeepEqual(got[types.DockSlotType], tt.want) {
+				t.Fatalf("dwellTimes() = %v, want %v", got[types.DockSlotType], tt.want)
+			}
+		})
+	}
+}
+
+func sortedUpTo(n int) []float64 {
+	values := make([]float64, 0, n)
+	for i := range n {
+		values = append(values, float64(i+1))
+	}
+
+	return values
+}
+
+// upTo returns the values from 1 to n in random order.
+func upTo(n int) []float64 {
+	values := sortedUpTo(n)
+	rand.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+	return values
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{name: "p50 of a single value", sorted: []float64{42}, p: 50, want: 42},
+		{name: "p99 of a single value", sorted: []float64{42}, p: 99, want: 42},
+		{name: "p0 of 100 values", sorted: sortedUpTo(100), p: 0, want: 1},
+		{name: "p50 of 100 values", sorted: sortedUpTo(100), p: 50, want: 50},
+		{name: "p99 of 100 values", sorted: sortedUpTo(100), p: 99, want: 99},
+		{name: "p100 of 100 values", sorted: sortedUpTo(100), p: 100, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Fatalf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations map[types.SlotType][]float64
+		want      []types.DurationStats
+	}{
+		{
+			name:      "no durations",
+			durations: map[types.SlotType][]float64{},
+			want:      []types.DurationStats{{SlotType: types.DockSlotType}, {SlotType: types.HelipadSlotType}},
+		},
+		{
+			name:      "single value",
+			durations: map[types.SlotType][]float64{types.HelipadSlotType: {42}},
+			want: []types.DurationStats{
+				{SlotType: types.DockSlotType},
+				{SlotType: types.HelipadSlotType, Count: 1, MeanSeconds: 42, P50Seconds: 42, P90Seconds: 42, P95Seconds: 42, P99Seconds: 42, MaxSeconds: 42},
+			},
+		},
+		{
+			name:      "100 unsorted values",
+			durations: map[types.SlotType][]float64{types.DockSlotType: upTo(100)},
+			want: []types.DurationStats{
+				{SlotType: types.DockSlotType, Count: 100, MeanSeconds: 50.5, P50Seconds: 50, P90Seconds: 90, P95Seconds: 95, P99Seconds: 99, MaxSeconds: 100},
+				{SlotType: types.HelipadSlotType},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := durationStats(tt.durations); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("durationStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
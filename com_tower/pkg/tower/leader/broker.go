@@ -0,0 +1,24 @@
+package leader
+
+import (
+	"fmt"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func declareRequestsExchange() error {
+	if err := config.Configuration.GetRabbitMQChannel().ExchangeDeclare(
+		"requests",
+		amqp.ExchangeDirect,
+		false,
+		false,
+		false,
+		false,
+		nil,
+	); err != nil {
+		return fmt.Errorf("failed to declare exchange \"requests\": %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package leader
+
+import "sync"
+
+// in-flight slot acquisitions are drained before leadership is handed over
+type slotGate struct {
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+}
+
+func newSlotGate() *slotGate {
+	return &slotGate{}
+}
+
+// Enter callers must call Leave once done.
+func (g *slotGate) Enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return false
+	}
+
+	g.inFlight.Add(1)
+	return true
+}
+
+func (g *slotGate) Leave() {
+	g.inFlight.Done()
+}
+
+func (g *slotGate) Close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+
+	g.inFlight.Wait()
+}
+
+func (g *slotGate) Open() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = false
+}
@@ -1,8 +1,11 @@
 package leader
 
 import (
+	"encoding/csv"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
@@ -27,23 +30,67 @@ func (h handler) MarkTowerAsAlive(ctx *gin.Context) {
 		return
 	}
 
-	if err := h.service.MarkTowerAsAlive(ctx, request.Id); err != nil {
+	if err := h.service.MarkTowerAsAlive(ctx, request); err != nil {
 		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusNoContent, nil)
+	ctx.JSON(http.StatusOK, types.TowerHealthResponse{Version: h.service.GetStateVersion()})
+}
+
+func (h handler) GetStateSnapshot(ctx *gin.Context) {
+	snapshot, err := h.service.GetStateSnapshot(ctx)
+	if err != nil {
+		log.Printf("failed to get state snapshot: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, snapshot)
 }
 
 func (h handler) ListHealthyTowers(ctx *gin.Context) {
-	towers, err := h.service.ListHealthyTowers(ctx)
+	towers, err := h.service.ListTowerStatuses(ctx)
 	if err != nil {
 		log.Printf("failed to list healthy towers: %v", err)
 		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
 		return
 	}
 
-	response := types.TowersPayload{Towers: towers}
+	response := types.TowerStatusesPayload{Towers: towers}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) GetClusterView(ctx *gin.Context) {
+	view, err := h.service.GetClusterView(ctx)
+	if err != nil {
+		log.Printf("failed to get cluster view: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, view)
+}
+
+func (h handler) GetReconciliation(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.GetReconciliation())
+}
+
+func (h handler) ListFreeSlots(ctx *gin.Context) {
+	var request types.FreeSlotsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	response, err := h.service.ListFreeSlots(ctx, request)
+	if err != nil {
+		log.Printf("failed to list free slots: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, response)
 }
 
@@ -65,6 +112,194 @@ func (h handler) AcquireSlot(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+func (h handler) PreemptSlot(ctx *gin.Context) {
+	var request types.AcquireSlotRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	response, err := h.service.PreemptSlot(ctx, request)
+	if err != nil {
+		log.Printf("failed to preempt slot: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) CreateBooking(ctx *gin.Context) {
+	var request types.BookingRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	response, err := h.service.CreateBooking(ctx, request)
+	if err != nil {
+		log.Printf("failed to create booking: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) CancelBooking(ctx *gin.Context) {
+	var request types.CancelBookingRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	if err := h.service.CancelBooking(ctx, request); err != nil {
+		log.Printf("failed to cancel booking: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h handler) ListBookings(ctx *gin.Context) {
+	var query types.BookingsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil || query.StructureUUID == (types.UUID{}) {
+		log.Printf("failed to parse bookings query: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	from := time.Now()
+	if query.From != nil {
+		from = *query.From
+	}
+
+	response, err := h.service.ListBookings(ctx, query.StructureUUID, from, query.To)
+	if err != nil {
+		log.Printf("failed to list bookings: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) GetUtilization(ctx *gin.Context) {
+	query, from, to, ok := bindAnalyticsQuery(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := h.service.GetUtilization(ctx, query.StructureUUID, from, to)
+	if err != nil {
+		log.Printf("failed to get utilization: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	writeAnalytics(ctx, query.Format, "utilization", response, utilizationRecords(*response))
+}
+
+func (h handler) GetDwellTimes(ctx *gin.Context) {
+	query, from, to, ok := bindAnalyticsQuery(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := h.service.GetDwellTimes(ctx, query.StructureUUID, from, to)
+	if err != nil {
+		log.Printf("failed to get dwell times: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	writeAnalytics(ctx, query.Format, "dwell-times", response, durationStatsRecords(*response))
+}
+
+func (h handler) GetTurnaround(ctx *gin.Context) {
+	query, from, to, ok := bindAnalyticsQuery(ctx)
+	if !ok {
+		return
+	}
+
+	response, err := h.service.GetTurnaround(ctx, query.StructureUUID, from, to)
+	if err != nil {
+		log.Printf("failed to get turnaround: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	writeAnalytics(ctx, query.Format, "turnaround", response, durationStatsRecords(*response))
+}
+
+func bindAnalyticsQuery(ctx *gin.Context) (query types.AnalyticsQuery, from time.Time, to time.Time, ok bool) {
+	if err := ctx.ShouldBindQuery(&query); err != nil || query.StructureUUID == (types.UUID{}) {
+		log.Printf("failed to parse analytics query: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return query, from, to, false
+	}
+
+	to = time.Now()
+	if query.To != nil {
+		to = *query.To
+	}
+
+	from = to.Add(-defaultAnalyticsRange)
+	if query.From != nil {
+		from = *query.From
+	}
+
+	switch {
+	case !from.Before(to):
+		log.Printf("invalid analytics range from %s to %s", from, to)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return query, from, to, false
+	case query.Format != "" && query.Format != types.JSONAnalyticsFormat && query.Format != types.CSVAnalyticsFormat:
+		log.Printf("invalid analytics format %s", query.Format)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return query, from, to, false
+	}
+
+	return query, from, to, true
+}
+
+func writeAnalytics(ctx *gin.Context, format types.AnalyticsFormat, name string, response any, records [][]string) {
+	if format != types.CSVAnalyticsFormat {
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+	ctx.Status(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	if err := writer.WriteAll(records); err != nil {
+		log.Printf("failed to write %s csv: %v", name, err)
+	}
+}
+
+func (h handler) OccupySlot(ctx *gin.Context) {
+	var request types.OccupySlotRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	if err := h.service.OccupySlot(ctx, request); err != nil {
+		log.Printf("failed to occupy slot: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
 func (h handler) ReleaseSlot(ctx *gin.Context) {
 	var request types.ReleaseSlotLockRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
@@ -81,3 +316,61 @@ func (h handler) ReleaseSlot(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+func (h handler) EnqueueWaitlist(ctx *gin.Context) {
+	var request types.WaitlistRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	response, err := h.service.EnqueueWaitlist(ctx, request)
+	if err != nil {
+		log.Printf("failed to enqueue vehicle: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) LeaveWaitlist(ctx *gin.Context) {
+	var request types.LeaveWaitlistRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	if err := h.service.LeaveWaitlist(ctx, request); err != nil {
+		log.Printf("failed to remove vehicle from waitlist: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h handler) TransferLeadership(ctx *gin.Context) {
+	var request types.TransferLeadershipRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	if err := h.service.TransferLeadership(ctx, request.TowerUUID); err != nil {
+		log.Printf("failed to transfer leadership: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// HandlePreVote always answers reachable: a candidate asking the leader itself
+// reaches it.
+func (h handler) HandlePreVote(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, types.PreVoteResponse{LeaderReachable: true})
+}
@@ -0,0 +1,55 @@
+package leader
+
+import (
+	"context"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+)
+
+type resultStore struct {
+	repository repository
+}
+
+// the raft leader does not write to Postgres, so results are kept in memory
+func newResultStore(r repository) idempotency.Store {
+	if consensus.Enabled() {
+		return idempotency.NewMemoryStore(config.Configuration.GetIdempotencyTTL())
+	}
+
+	return resultStore{repository: r}
+}
+
+func (s resultStore) Claim(ctx context.Context, key string, requestHash string) (*idempotency.Result, error) {
+	claimed, err := s.repository.ClaimIdempotencyKey(ctx, key, requestHash, config.Configuration.GetIdempotencyTTL(), idempotency.ClaimTimeout)
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	// a key missing here was released after the claim failed, so the caller
+	// tries to claim it again
+	result, found, err := s.repository.GetIdempotentResult(ctx, key, config.Configuration.GetIdempotencyTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, utils.ErrIdempotencyKeyBusy
+	}
+
+	if result.Pending {
+		return result, utils.ErrIdempotencyKeyBusy
+	}
+
+	return result, nil
+}
+
+func (s resultStore) Put(ctx context.Context, key string, result idempotency.Result) error {
+	return s.repository.PutIdempotentResult(ctx, key, result)
+}
+
+func (s resultStore) Release(ctx context.Context, key string) error {
+	return s.repository.ReleaseIdempotencyKey(ctx, key)
+}
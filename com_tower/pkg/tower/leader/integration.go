@@ -0,0 +1,201 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type integration struct {
+	client *http.Client
+}
+
+func newIntegration() integration {
+	return integration{
+		client: &http.Client{},
+	}
+}
+
+func (i integration) GetClusterStatus(ctx context.Context, towerUuid types.UUID) (*types.ClusterStatus, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/cluster", towerUuid.String(), config.Configuration.GetBaseDns())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cluster status request for tower %s: %w", towerUuid.String(), err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request cluster status for tower %s: %w", towerUuid.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var status types.ClusterStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return nil, fmt.Errorf("failed to decode cluster status response body for tower %s: %w", towerUuid.String(), err)
+		}
+
+		return &status, nil
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) RequestSlotToStructure(ctx context.Context, structureUuid types.UUID, structureType types.StructureType, slotRequest types.StructureSlotRequest) (*types.SlotResponse, error) {
+	url := fmt.Sprintf("http://s-%s.%s.%s/slots", structureUuid.String(), structureType, config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(slotRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal slot request for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create slot request for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request a slot for %s %s: %w: %w", structureType, structureUuid.String(), utils.ErrStructureUnreachable, err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var slotResp types.SlotResponse
+		if err := json.NewDecoder(resp.Body).Decode(&slotResp); err != nil {
+			return nil, fmt.Errorf("failed to decode response body for %s %s: %w", structureType, structureUuid.String(), err)
+		}
+
+		return &slotResp, nil
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+// ListStructureSlots asks the structure for the state of each of its slots.
+func (i integration) ListStructureSlots(ctx context.Context, structureUuid types.UUID, structureType types.StructureType) (*types.StructureSlotsResponse, error) {
+	url := fmt.Sprintf("http://s-%s.%s.%s/slots", structureUuid.String(), structureType, config.Configuration.GetBaseDns())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list slots request for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slots of %s %s: %w: %w", structureType, structureUuid.String(), utils.ErrStructureUnreachable, err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var slotsResp types.StructureSlotsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&slotsResp); err != nil {
+			return nil, fmt.Errorf("failed to decode list slots response body for %s %s: %w", structureType, structureUuid.String(), err)
+		}
+
+		return &slotsResp, nil
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) ReleaseSlot(ctx context.Context, structureUuid types.UUID, structureType types.StructureType, slotRequest types.ReleaseSlotRequest) error {
+	url := fmt.Sprintf("http://s-%s.%s.%s/release-slot", structureUuid.String(), structureType, config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(slotRequest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal release slot request for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create release slot request for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request a slot release for %s %s: %w", structureType, structureUuid.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		if _, err = io.Copy(io.Discard, resp.Body); err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		return nil
+
+	default:
+		return utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) PublishWaitlistGrant(ctx context.Context, grant types.WaitlistGrantMessage) error {
+	body, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal waitlist grant message body: %w", err)
+	}
+
+	payload := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	}
+
+	if err := config.Configuration.GetRabbitMQChannel().PublishWithContext(ctx, "requests", "waitlist", false, false, payload); err != nil {
+		return fmt.Errorf("failed to send waitlist grant message to the broker: %w", err)
+	}
+
+	return nil
+}
+
+func (i integration) PublishPreemption(ctx context.Context, preemption types.PreemptionMessage) error {
+	body, err := json.Marshal(preemption)
+	if err != nil {
+		return fmt.Errorf("failed to marshal preemption message body: %w", err)
+	}
+
+	payload := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	}
+
+	if err := config.Configuration.GetRabbitMQChannel().PublishWithContext(ctx, "requests", "preempted", false, false, payload); err != nil {
+		return fmt.Errorf("failed to send preemption message to the broker: %w", err)
+	}
+
+	return nil
+}
+
+func (i integration) PublishAudit(ctx context.Context, audit types.AuditRequest) error {
+	body, err := json.Marshal(audit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit request message body: %w", err)
+	}
+
+	payload := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	}
+
+	if err := config.Configuration.GetRabbitMQChannel().PublishWithContext(ctx, "requests", "requests", false, false, payload); err != nil {
+		return fmt.Errorf("failed to send audit request message to the broker: %w", err)
+	}
+
+	return nil
+}
@@ -4,33 +4,49 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
 
+const (
+	clusterStatusTimeout    = 3 * time.Second
+	reservationReapInterval = 10 * time.Second
+)
+
 var (
 	client = &http.Client{}
 )
 
-func InitLeader(ctx context.Context) func() {
+func InitLeader(ctx context.Context, elector leaderelection.Elector) func() {
 	leaderCtx, leaderCancel := context.WithCancel(ctx)
 
 	repo := newRepository()
-	svc := newService(repo)
+	integ := newIntegration()
+	svc := newService(leaderCtx, repo, integ, elector)
 	if err := svc.AcquireLock(leaderCtx); err != nil {
 		log.Fatalf("[leader] failed to acquire database lock: %v", err)
 	}
 
+	log.Printf("[leader] leading term %d", config.Configuration.GetLeaderTerm())
+
+	if err := declareRequestsExchange(); err != nil {
+		log.Printf("[leader][waitlist] failed to declare requests exchange: %v", err)
+	}
+
 	server := &http.Server{
-		Handler:        setupRouter(svc),
+		Handler:        setupRouter(svc, newResultStore(repo)),
 		Addr:           fmt.Sprintf(":%s", os.Getenv(utils.PortEnv)),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
@@ -38,15 +54,24 @@ func InitLeader(ctx context.Context) func() {
 	}
 
 	go serve(server)
-	go propagate(leaderCtx, svc)
-	go renewLock(leaderCtx, svc)
+	go reapReservations(leaderCtx, svc)
+	go reconcile(leaderCtx, svc)
+	if consensus.Enabled() {
+		go replicate(leaderCtx, svc)
+	} else {
+		go propagate(leaderCtx, svc)
+		go renewLock(leaderCtx, svc)
+		go watchQuorum(leaderCtx, svc)
+	}
 
 	return func() {
-		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 2*time.Second)
-    	defer releaseCancel()
+		if !consensus.Enabled() {
+			releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer releaseCancel()
 
-		if err := svc.ReleaseLock(releaseCtx); err != nil {
-			log.Printf("[leader] failed to release database lock: %v", err)
+			if err := svc.ReleaseLock(releaseCtx); err != nil {
+				log.Printf("[leader] failed to release database lock: %v", err)
+			}
 		}
 		leaderCancel()
 
@@ -58,6 +83,9 @@ func InitLeader(ctx context.Context) func() {
 		} else {
 			log.Println("[leader] HTTP Server stopped")
 		}
+
+		// grants started by requests end early on the canceled context
+		svc.Wait()
 	}
 }
 
@@ -67,6 +95,8 @@ func serve(server *http.Server) {
 	}
 }
 
+// each worker propagates to one tower at a time, so a hung tower only holds up
+// its own worker until its deadline
 func propagate(ctx context.Context, svc service) {
 	for {
 		select {
@@ -77,38 +107,70 @@ func propagate(ctx context.Context, svc service) {
 				break
 			}
 
-			towersPayload, err := json.Marshal(types.TowersPayload{Towers: healthyTowers})
-			if err != nil {
-				log.Printf("[leader][propagate] failed to marshal healthy towers payload: %v", err)
-				break
-			}
-
 			structures, err := svc.ListStructures(ctx)
 			if err != nil {
 				log.Printf("[leader][propagate] failed to list structures: %v", err)
 				break
 			}
 
-			structuresPayload, err := json.Marshal(structures)
-			if err != nil {
-				log.Printf("[leader][propagate] failed to marshal structures payload: %v", err)
-				break
+			svc.state.Update(healthyTowers, *structures)
+
+			jobs := make(chan types.UUID)
+			var wg sync.WaitGroup
+			for range min(config.Configuration.GetPropagationParallelism(), len(healthyTowers)) {
+				wg.Go(func() {
+					for towerUuid := range jobs {
+						propagateTo(ctx, svc, towerUuid)
+					}
+				})
 			}
 
+		enqueue:
 			for _, tower := range healthyTowers {
-				baseEndpoint := fmt.Sprintf("http://t-%s.tower.%s", tower.UUID.String(), config.Configuration.GetBaseDns())
-				towersEndpoint := fmt.Sprintf("%s/%s", baseEndpoint, "towers")
-				structuresEndpoint := fmt.Sprintf("%s/%s", baseEndpoint, "structures")
-
-				if err := doPropagateReq(ctx, towersEndpoint, towersPayload); err != nil {
-					log.Printf("[leader][propagate] failed to propagate healthy towers to tower %s: %v", tower.UUID.String(), err)
+				if tower.UUID == config.Configuration.GetId() {
+					continue
 				}
 
-				if err := doPropagateReq(ctx, structuresEndpoint, structuresPayload); err != nil {
-					log.Printf("[leader][propagate] failed to propagate structures to tower %s: %v", tower.UUID.String(), err)
+				select {
+				case jobs <- tower.UUID:
+				case <-ctx.Done():
+					break enqueue
 				}
 			}
 
+			close(jobs)
+			wg.Wait()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func replicate(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(config.Configuration.GetPropagationInterval()):
+			towers, err := svc.ListTowers(ctx)
+			if err != nil {
+				log.Printf("[leader][replicate] failed to list towers: %v", err)
+				break
+			}
+
+			if err := consensus.Replica.SyncTowers(towers); err != nil {
+				log.Printf("[leader][replicate] failed to replicate towers: %v", err)
+			}
+
+			structures, err := svc.ListStructures(ctx)
+			if err != nil {
+				log.Printf("[leader][replicate] failed to list structures: %v", err)
+				break
+			}
+
+			if err := consensus.Replica.SyncStructures(*structures); err != nil {
+				log.Printf("[leader][replicate] failed to replicate structures: %v", err)
+			}
+
 		case <-ctx.Done():
 			return
 		}
@@ -116,11 +178,71 @@ func propagate(ctx context.Context, svc service) {
 }
 
 func renewLock(ctx context.Context, svc service) {
+	failureCount := 0
+
 	for {
 		select {
 		case <-time.After(config.Configuration.GetRenewLockInterval()):
 			if err := svc.RenewLock(ctx); err != nil {
-				log.Printf("[leader][renew_lock] failed to renew lock: %v", err)
+				failureCount++
+				log.Printf("[leader][renew_lock] failed to renew lock (%d consecutive failures): %v", failureCount, err)
+
+				if errors.Is(err, utils.ErrLockLost) || svc.IsLeaseExpired() {
+					stepDown(ctx, svc)
+					return
+				}
+
+				break
+			}
+
+			failureCount = 0
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func reapReservations(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(reservationReapInterval):
+			if err := svc.ExpireReservations(ctx); err != nil {
+				log.Printf("[leader][reservations] %v", err)
+			}
+
+			if err := svc.ExpireIdempotentResults(ctx); err != nil {
+				log.Printf("[leader][idempotency] %v", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func reconcile(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(config.Configuration.GetReconcileInterval()):
+			if err := svc.Reconcile(ctx); err != nil {
+				log.Printf("[leader][reconcile] %v", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// a raft leader cut off from the majority cannot commit slot changes, so raft
+// mode needs no watch
+func watchQuorum(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(config.Configuration.GetHeartbeatInterval()):
+			if err := svc.CheckQuorum(ctx); err != nil {
+				log.Printf("[leader][quorum] failed to check quorum: %v", err)
 			}
 
 		case <-ctx.Done():
@@ -129,12 +251,88 @@ func renewLock(ctx context.Context, svc service) {
 	}
 }
 
+// stepDown runs once the tower lock can no longer be proven to be held.
+func stepDown(ctx context.Context, svc service) {
+	log.Printf("[leader][renew_lock] lease for term %d expired, stepping down", config.Configuration.GetLeaderTerm())
+	svc.RevokeLease()
+
+	if err := svc.Resign(ctx); err != nil {
+		log.Printf("[leader][renew_lock] failed to resign leadership: %v", err)
+	}
+}
+
+func propagateTo(ctx context.Context, svc service, towerUuid types.UUID) {
+	baseEndpoint := fmt.Sprintf("http://t-%s.tower.%s", towerUuid.String(), config.Configuration.GetBaseDns())
+
+	var sent bool
+	var errs []error
+	if payload, ok := svc.state.TowersFor(towerUuid); ok {
+		sent = true
+		if err := propagatePayload(ctx, svc, towerUuid, fmt.Sprintf("%s/%s", baseEndpoint, "towers"), payload); err != nil {
+			errs = append(errs, fmt.Errorf("failed to propagate healthy towers: %w", err))
+		} else {
+			svc.state.TowersDelivered(towerUuid, *payload.Version)
+		}
+	}
+
+	if payload, ok := svc.state.StructuresFor(towerUuid); ok {
+		sent = true
+		if err := propagatePayload(ctx, svc, towerUuid, fmt.Sprintf("%s/%s", baseEndpoint, "structures"), payload); err != nil {
+			errs = append(errs, fmt.Errorf("failed to propagate structures: %w", err))
+		} else {
+			svc.state.StructuresDelivered(towerUuid, *payload.Version)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		failures := svc.propagation.Failed(towerUuid, err)
+		log.Printf("[leader][propagate] propagation to tower %s failed (%d consecutive failures): %v", towerUuid.String(), failures, err)
+		return
+	}
+
+	if sent {
+		svc.propagation.Succeeded(towerUuid)
+	}
+}
+
+// refusals are not retried: a tower refusing a delta gets the full state next
+func propagatePayload(ctx context.Context, svc service, towerUuid types.UUID, endpoint string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal propagation payload: %w", err)
+	}
+
+	backoff := propagationBackoff
+	for attempt := 0; ; attempt++ {
+		reqCtx, cancel := context.WithTimeout(ctx, config.Configuration.GetPropagationTimeout())
+		err = doPropagateReq(reqCtx, endpoint, body)
+		cancel()
+
+		if errors.Is(err, utils.ErrOutOfOrderState) {
+			svc.state.Forget(towerUuid)
+		}
+
+		if err == nil || errors.Is(err, utils.ErrOutOfOrderState) || errors.Is(err, utils.ErrStaleTerm) || attempt >= config.Configuration.GetPropagationRetries() {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
 func doPropagateReq(ctx context.Context, endpoint string, payload []byte) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(payload))
 	if err != nil {
 		return fmt.Errorf("failed to create propagation request: %w", err)
 	}
 
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute propagation request: %w", err)
@@ -146,5 +344,12 @@ func doPropagateReq(ctx context.Context, endpoint string, payload []byte) error
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	switch resp.StatusCode {
+	case http.StatusConflict:
+		return fmt.Errorf("propagation refused by tower: %w", utils.ErrStaleTerm)
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("propagation refused by tower: %w", utils.ErrOutOfOrderState)
+	}
+
 	return nil
 }
@@ -0,0 +1,43 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+)
+
+// lease is only renewed with the time taken before a renewal was sent, so it
+// always expires no later than the tower lock row does.
+type lease struct {
+	mu        sync.RWMutex
+	renewedAt time.Time
+	revoked   bool
+}
+
+func newLease() *lease {
+	return &lease{}
+}
+
+func (l *lease) Renew(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if at.After(l.renewedAt) {
+		l.renewedAt = at
+	}
+}
+
+func (l *lease) Revoke() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revoked = true
+}
+
+func (l *lease) Expired() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.revoked || time.Since(l.renewedAt) >= config.Configuration.GetRenewLockTimeout()
+}
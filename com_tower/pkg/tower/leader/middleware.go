@@ -0,0 +1,40 @@
+package leader
+
+import (
+	"crypto/subtle"
+	"log"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireCurrentTerm keeps minions from acting on a deposed leader.
+func RequireCurrentTerm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		term := utils.GetLeaderTermFromHeader(ctx.Request.Header)
+		if term != config.Configuration.GetLeaderTerm() {
+			log.Printf("[leader][term][middleware] rejecting %s request with term %d: current term is %d", ctx.Request.URL.Path, term, config.Configuration.GetLeaderTerm())
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrStaleTerm)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireAdminToken disables admin endpoints while no token is configured.
+func RequireAdminToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := config.Configuration.GetAdminToken()
+		if token == "" || subtle.ConstantTimeCompare([]byte(ctx.GetHeader(utils.AdminTokenHeader)), []byte(token)) != 1 {
+			log.Printf("[leader][admin][middleware] rejecting %s request from %s: missing or invalid admin token", ctx.Request.URL.Path, ctx.ClientIP())
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrUnauthorized)
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// doubled on every further retry
+const propagationBackoff = 500 * time.Millisecond
+
+type propagationHealth struct {
+	mu     sync.RWMutex
+	towers map[types.UUID]types.TowerPropagation
+}
+
+func newPropagationHealth() *propagationHealth {
+	return &propagationHealth{
+		towers: make(map[types.UUID]types.TowerPropagation),
+	}
+}
+
+func (h *propagationHealth) Succeeded(id types.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	health := h.towers[id]
+	health.LastSuccessAt = &now
+	health.ConsecutiveFailures = 0
+	health.LastError = ""
+	h.towers[id] = health
+}
+
+func (h *propagationHealth) Failed(id types.UUID, err error) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	health := h.towers[id]
+	health.LastFailureAt = &now
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	h.towers[id] = health
+	return health.ConsecutiveFailures
+}
+
+func (h *propagationHealth) Get(id types.UUID) (types.TowerPropagation, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	health, ok := h.towers[id]
+	return health, ok
+}
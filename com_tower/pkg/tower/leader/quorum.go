@@ -0,0 +1,64 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// towers are given one heartbeat timeout after the leader starts to report in
+type quorum struct {
+	mu        sync.RWMutex
+	seenAt    map[types.UUID]time.Time
+	startedAt time.Time
+	readOnly  bool
+}
+
+func newQuorum() *quorum {
+	return &quorum{
+		seenAt:    make(map[types.UUID]time.Time),
+		startedAt: time.Now(),
+	}
+}
+
+func (q *quorum) Seen(id types.UUID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seenAt[id] = time.Now()
+}
+
+// Evaluate counts this leader as alive.
+func (q *quorum) Evaluate(towers []types.Tower) (alive int, size int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	timeout := config.Configuration.GetHeartbeatTimeout()
+	alive = 1
+	for _, tower := range towers {
+		if tower.UUID == config.Configuration.GetId() {
+			continue
+		}
+
+		if seenAt, ok := q.seenAt[tower.UUID]; ok && time.Since(seenAt) < timeout {
+			alive++
+		}
+	}
+
+	size = config.Configuration.GetQuorumSize()
+	if size <= 0 {
+		size = len(towers)/2 + 1
+	}
+
+	q.readOnly = alive < size && time.Since(q.startedAt) >= timeout
+	return alive, size
+}
+
+func (q *quorum) ReadOnly() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.readOnly
+}
@@ -0,0 +1,88 @@
+package leader
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// divergences are only confirmed when the next run finds them again, so slots
+// caught between the structure and the leader by an in-flight request are
+// never repaired
+type reconciler struct {
+	mu      sync.Mutex
+	status  types.ReconciliationStatus
+	pending map[string]types.Divergence
+}
+
+func newReconciler() *reconciler {
+	return &reconciler{
+		status: types.ReconciliationStatus{
+			Policy:      config.Configuration.GetReconcilePolicy(),
+			Divergences: []types.Divergence{},
+		},
+		pending: make(map[string]types.Divergence),
+	}
+}
+
+func divergenceKey(d types.Divergence) string {
+	return fmt.Sprintf("%s/%s/%d/%s", d.StructureUUID.String(), d.SlotType, d.SlotNumber, d.Type)
+}
+
+func (r *reconciler) Record(found []types.Divergence) (detected []types.Divergence, confirmed []types.Divergence) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	pending := make(map[string]types.Divergence, len(found))
+	for _, divergence := range found {
+		key := divergenceKey(divergence)
+		if previous, ok := r.pending[key]; ok {
+			divergence.DetectedAt = previous.DetectedAt
+			confirmed = append(confirmed, divergence)
+		} else {
+			divergence.DetectedAt = now
+			detected = append(detected, divergence)
+		}
+
+		pending[key] = divergence
+	}
+
+	r.pending = pending
+	r.status.Runs++
+	r.status.LastRunAt = &now
+	r.status.DivergencesFound += len(detected)
+	r.status.Divergences = slices.Concat(confirmed, detected)
+	return detected, confirmed
+}
+
+func (r *reconciler) Repaired(ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ok {
+		r.status.Repaired++
+	} else {
+		r.status.RepairFailures++
+	}
+}
+
+func (r *reconciler) StructureFailed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.StructureFailures++
+}
+
+func (r *reconciler) Status() types.ReconciliationStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := r.status
+	status.Divergences = slices.Clone(r.status.Divergences)
+	return status
+}
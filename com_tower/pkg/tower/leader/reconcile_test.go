@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"testing"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/google/uuid"
+)
+
+func TestReconcilerRecord(t *testing.T) {
+	structure := types.UUID(uuid.New())
+	divergence := func(slotNumber int, divergenceType types.DivergenceType) types.Divergence {
+		return types.Divergence{Type: divergenceType, StructureUUID: structure, SlotType: types.DockSlotType, SlotNumber: slotNumber}
+	}
+
+	free := divergence(1, types.FreeInStructureDivergenceType)
+	unheld := divergence(1, types.UnheldInLeaderDivergenceType)
+	other := divergence(2, types.FreeInStructureDivergenceType)
+
+	tests := []struct {
+		name        string
+		runs        [][]types.Divergence
+		detected    int
+		confirmed   int
+		divergences int
+		found       int
+	}{
+		{name: "first run only detects", runs: [][]types.Divergence{{free}}, detected: 1, divergences: 1, found: 1},
+		{name: "found again by the next run is confirmed", runs: [][]types.Divergence{{free}, {free}}, confirmed: 1, divergences: 1, found: 1},
+		{name: "not found by the next run disappears", runs: [][]types.Divergence{{free}, {}}, found: 1},
+		{name: "found after disappearing is detected again", runs: [][]types.Divergence{{free}, {}, {free}}, detected: 1, divergences: 1, found: 2},
+		{name: "another divergence on the slot is not confirmed", runs: [][]types.Divergence{{free}, {unheld}}, detected: 1, divergences: 1, found: 2},
+		{name: "only the divergences found again are confirmed", runs: [][]types.Divergence{{free, other}, {free, unheld}}, detected: 1, confirmed: 1, divergences: 2, found: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &reconciler{
+				status:  types.ReconciliationStatus{Divergences: []types.Divergence{}},
+				pending: make(map[string]types.Divergence),
+			}
+
+			var detected, confirmed []types.Divergence
+			for _, run := range tt.runs {
+				detected, confirmed = r.Record(run)
+			}
+
+			if len(detected) != tt.detected || len(confirmed) != tt.confirmed {
+				t.Fatalf("last run detected %d and confirmed %d divergences, want %d and %d", len(detected), len(confirmed), tt.detected, tt.confirmed)
+			}
+
+			status := r.Status()
+			if status.Runs != len(tt.runs) || len(status.Divergences) != tt.divergences || status.DivergencesFound != tt.found {
+				t.Fatalf("status after %d runs = %+v, want %d divergences and %d found", len(tt.runs), status, tt.divergences, tt.found)
+			}
+		})
+	}
+}
+
+func TestReconcilerKeepsWhenADivergenceWasDetected(t *testing.T) {
+	r := &reconciler{pending: make(map[string]types.Divergence)}
+	divergence := types.Divergence{Type: types.FreeInStructureDivergenceType, StructureUUID: types.UUID(uuid.New()), SlotType: types.DockSlotType, SlotNumber: 1}
+
+	detected, _ := r.Record([]types.Divergence{divergence})
+	_, confirmed := r.Record([]types.Divergence{divergence})
+
+	if len(confirmed) != 1 || !confirmed[0].DetectedAt.Equal(detected[0].DetectedAt) {
+		t.Fatalf("confirmed divergence %+v, want it detected at %s", confirmed, detected[0].DetectedAt)
+	}
+}
@@ -3,16 +3,42 @@ package leader
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 const (
-	listStructuresQuery = "SELECT st.id, st.latitude, st.longitude, jsonb_build_object('docks_qtt', COUNT(*) FILTER (WHERE sl.type = 'dock'), 'helipads_qtt', COUNT(*) FILTER (WHERE sl.type = 'helipad')) AS slots FROM structures st LEFT JOIN slots sl ON st.id = sl.structure_id WHERE st.type = $1 GROUP BY st.id;"
+	// fences writes to the leader holding the tower lock in the given term
+	holdsLockCondition = "EXISTS (SELECT 1 FROM tower_lock WHERE leader_id = $3 AND term = $4)"
+	// another transaction took the slot concurrently
+	serializationFailureCode = "40001"
+	uniqueViolationCode      = "23505"
+	// a booking overlaps another booking of the slot
+	exclusionViolationCode  = "23P01"
+	expireReservationsQuery = "UPDATE vehicles v SET current_slot_id = NULL, reserved_until = NULL FROM slots sl JOIN structures st ON st.id = sl.structure_id WHERE v.current_slot_id = sl.id AND v.reserved_until < NOW() AND EXISTS (SELECT 1 FROM tower_lock WHERE leader_id = $1 AND term = $2) RETURNING v.id AS vehicle_id, sl.id AS slot_id, sl.structure_id, lower(st.type) AS structure_type, sl.type::text AS slot_type, sl.number AS slot_number;"
+	slotReservationQuery    = "SELECT v.id AS vehicle_id, sl.structure_id, lower(st.type) AS structure_type, sl.type::text AS slot_type, sl.number AS slot_number FROM vehicles v JOIN slots sl ON sl.id = v.current_slot_id JOIN structures st ON st.id = sl.structure_id WHERE sl.id = $1 AND v.reserved_until IS NOT NULL;"
+	heldSlotsQuery          = "SELECT v.id AS vehicle_id, sl.structure_id, lower(st.type) AS structure_type, sl.type::text AS slot_type, sl.number AS slot_number FROM vehicles v JOIN slots sl ON sl.id = v.current_slot_id JOIN structures st ON st.id = sl.structure_id WHERE sl.structure_id = $1;"
+	recordTransitionQuery   = "INSERT INTO slot_history (vehicle_id, structure_id, slot_type, slot_number, transition, term) VALUES ($1, $2, $3, $4, $5, $6);"
+	// slot history queries
+	slotVisitsQuery = "SELECT vehicle_id, slot_type, slot_number, MIN(recorded_at) AS acquired_at, MIN(recorded_at) FILTER (WHERE transition = 'arrived') AS arrived_at, MIN(recorded_at) FILTER (WHERE transition IN ('departed', 'released')) AS ended_at, (ARRAY_AGG(transition ORDER BY recorded_at, id) FILTER (WHERE transition IN ('departed', 'released')))[1] AS ended_by FROM (SELECT *, COUNT(*) FILTER (WHERE transition = 'acquired') OVER (PARTITION BY vehicle_id, slot_type, slot_number ORDER BY recorded_at, id) AS visit FROM slot_history WHERE structure_id = $1) h WHERE visit > 0 GROUP BY vehicle_id, slot_type, slot_number, visit HAVING MIN(recorded_at) < $3 AND COALESCE(MIN(recorded_at) FILTER (WHERE transition IN ('departed', 'released')), 'infinity') >= $2 ORDER BY acquired_at;"
+	// waitlist offer queries
+	offerSlotQuery    = "UPDATE waitlist SET offered_slot_id = $1, offered_until = $2 WHERE vehicle_id = (SELECT w.vehicle_id FROM waitlist w JOIN slots sl ON sl.structure_id = w.structure_id AND sl.type::text = w.slot_type WHERE sl.id = $1 AND w.offered_slot_id IS NULL ORDER BY w.priority DESC, w.enqueued_at LIMIT 1);"
+	offeredToQuery    = "SELECT vehicle_id, structure_id, structure_type, slot_type, priority, enqueued_at FROM waitlist WHERE offered_slot_id = $1 AND offered_until > NOW();"
+	expireOffersQuery = "UPDATE waitlist SET offered_slot_id = NULL, offered_until = NULL WHERE offered_until <= NOW();"
+	// waitlist and booking queries
+	enqueueWaitlistQuery  = "INSERT INTO waitlist (vehicle_id, structure_id, structure_type, slot_type, priority, enqueued_at) SELECT $1, $2, $3, $4, $5, NOW() WHERE EXISTS (SELECT 1 FROM tower_lock WHERE leader_id = $6 AND term = $7) ON CONFLICT (vehicle_id) DO UPDATE SET structure_id = EXCLUDED.structure_id, structure_type = EXCLUDED.structure_type, slot_type = EXCLUDED.slot_type, priority = EXCLUDED.priority, enqueued_at = CASE WHEN waitlist.structure_id = EXCLUDED.structure_id AND waitlist.slot_type = EXCLUDED.slot_type AND waitlist.priority = EXCLUDED.priority THEN waitlist.enqueued_at ELSE EXCLUDED.enqueued_at END;"
+	waitlistPositionQuery = "SELECT COUNT(*) FROM waitlist w JOIN waitlist me ON w.structure_id = me.structure_id AND w.slot_type = me.slot_type WHERE me.vehicle_id = $1 AND (w.priority > me.priority OR (w.priority = me.priority AND w.enqueued_at <= me.enqueued_at));"
+	createBookingQuery    = "INSERT INTO bookings (vehicle_id, slot_id, starts_at, ends_at) SELECT $1, sl.id, $5, $6 FROM slots sl WHERE sl.structure_id = $2 AND sl.type = $7 AND sl.number = $8 AND " + holdsLockCondition + " RETURNING id;"
+	listBookingsQuery     = "SELECT b.id, b.vehicle_id, sl.structure_id, lower(st.type) AS structure_type, sl.type::text AS slot_type, sl.number AS slot_number, b.starts_at, b.ends_at, b.status FROM bookings b JOIN slots sl ON sl.id = b.slot_id JOIN structures st ON st.id = sl.structure_id"
+	listStructuresQuery   = "SELECT st.id, st.latitude, st.longitude, jsonb_build_object('docks_qtt', COUNT(*) FILTER (WHERE sl.type = 'dock'), 'helipads_qtt', COUNT(*) FILTER (WHERE sl.type = 'helipad')) AS slots FROM structures st LEFT JOIN slots sl ON st.id = sl.structure_id WHERE st.type = $1 GROUP BY st.id;"
 )
 
 type repository struct {
@@ -39,6 +65,15 @@ func (r repository) UpdateTowerLastSeen(ctx context.Context, id types.UUID) (err
 	return
 }
 
+func (r repository) ListTowers(ctx context.Context) ([]types.Tower, error) {
+	rows, err := r.DB.Query(ctx, "SELECT id, latitude, longitude FROM towers;")
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[types.Tower])
+}
+
 func (r repository) ListTowersByLastSeenAt(ctx context.Context, heartbeatTimeout int) ([]types.Tower, error) {
 	rows, err := r.DB.Query(ctx, "SELECT id, latitude, longitude FROM towers WHERE last_seen_at >= (NOW() - ($1 || ' seconds')::interval);", strconv.Itoa(heartbeatTimeout))
 	if err != nil {
@@ -71,13 +106,147 @@ func (r repository) GetSlotUUID(ctx context.Context, structureUuid types.UUID, s
 	return
 }
 
-func (r repository) CheckSlotAvailability(ctx context.Context, slotUuid types.UUID) (isAvailable bool, err error) {
-	err = r.DB.QueryRow(ctx, "SELECT NOT EXISTS (SELECT 1 FROM vehicles WHERE current_slot_id = $1);", slotUuid.String()).Scan(&isAvailable)
-	return
+func (r repository) ListFreeSlots(ctx context.Context, structureUuid types.UUID, slotType types.SlotType) ([]int, error) {
+	rows, err := r.DB.Query(ctx, "SELECT sl.number FROM slots sl WHERE sl.structure_id = $1 AND sl.type = $2 AND NOT EXISTS (SELECT 1 FROM vehicles WHERE current_slot_id = sl.id) AND NOT EXISTS (SELECT 1 FROM bookings WHERE slot_id = sl.id AND status = 'booked' AND starts_at <= NOW() AND ends_at > NOW()) ORDER BY sl.number;", structureUuid.String(), slotType)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// AcquireSlot yields the conflict result instead of an error when it loses a
+// race against another acquisition of the slot.
+func (r repository) AcquireSlot(ctx context.Context, request types.AcquireSlotRequest, term int64, reservedUntil time.Time) (types.AcquireSlotResultType, error) {
+	result, err := r.acquireSlot(ctx, request, term, reservedUntil)
+	if isSlotConflict(err) {
+		return types.ConflictAcquireSlotResultType, nil
+	}
+
+	return result, err
+}
+
+func (r repository) acquireSlot(ctx context.Context, request types.AcquireSlotRequest, term int64, reservedUntil time.Time) (types.AcquireSlotResultType, error) {
+	tx, err := r.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var slotUuid types.UUID
+	err = tx.QueryRow(ctx, "SELECT id FROM slots WHERE structure_id = $1 AND type = $2 AND number = $3 FOR UPDATE;", request.StructureUUID.String(), request.SlotType, strconv.Itoa(request.SlotNumber)).Scan(&slotUuid)
+	if err != nil {
+		return "", fmt.Errorf("failed to get slot uuid: %w", err)
+	}
+
+	var isOccupied bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM vehicles WHERE current_slot_id = $1 AND id <> $2);", slotUuid.String(), request.VehicleUUID.String()).Scan(&isOccupied)
+	if err != nil {
+		return "", fmt.Errorf("failed to check slot %s availability: %w", slotUuid.String(), err)
+	}
+
+	if isOccupied {
+		return types.UnavailableAcquireSlotResultType, nil
+	}
+
+	// the reservation must not run into a booking of another vehicle
+	var isBooked bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM bookings WHERE slot_id = $1 AND vehicle_id <> $2 AND status = 'booked' AND starts_at < $3 AND ends_at > NOW());", slotUuid.String(), request.VehicleUUID.String(), reservedUntil).Scan(&isBooked)
+	if err != nil {
+		return "", fmt.Errorf("failed to check bookings of slot %s: %w", slotUuid.String(), err)
+	}
+
+	if isBooked {
+		return types.UnavailableAcquireSlotResultType, nil
+	}
+
+	// a slot released while vehicles wait is held back for the vehicle it was
+	// offered to, unless the request jumps the waitlist
+	var isWaitlisted bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM waitlist WHERE offered_slot_id = $1 AND offered_until > NOW() AND vehicle_id <> $2);", slotUuid.String(), request.VehicleUUID.String()).Scan(&isWaitlisted)
+	if err != nil {
+		return "", fmt.Errorf("failed to check waitlist of slot %s: %w", slotUuid.String(), err)
+	}
+
+	if isWaitlisted && !request.Priority.JumpsWaitlist() {
+		return types.WaitlistedAcquireSlotResultType, nil
+	}
+
+	if err := assignSlot(ctx, tx, slotUuid, request.VehicleUUID, term, reservedUntil); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return types.AcquiredAcquireSlotResultType, nil
 }
 
-func (r repository) AcquireSlot(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID) error {
-	tag, err := r.DB.Exec(ctx, "UPDATE vehicles SET current_slot_id = $1 WHERE id = $2;", slotUuid.String(), vehicleUuid.String())
+func (r repository) PreemptSlot(ctx context.Context, request types.AcquireSlotRequest, term int64, reservedUntil time.Time) (types.AcquireSlotResultType, *types.SlotReservation, error) {
+	result, displaced, err := r.preemptSlot(ctx, request, term, reservedUntil)
+	if isSlotConflict(err) {
+		return types.ConflictAcquireSlotResultType, nil, nil
+	}
+
+	return result, displaced, err
+}
+
+func (r repository) preemptSlot(ctx context.Context, request types.AcquireSlotRequest, term int64, reservedUntil time.Time) (types.AcquireSlotResultType, *types.SlotReservation, error) {
+	tx, err := r.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	var slotUuid types.UUID
+	err = tx.QueryRow(ctx, "SELECT id FROM slots WHERE structure_id = $1 AND type = $2 AND number = $3 FOR UPDATE;", request.StructureUUID.String(), request.SlotType, strconv.Itoa(request.SlotNumber)).Scan(&slotUuid)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get slot uuid: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, slotReservationQuery, slotUuid.String())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get reservation of slot %s: %w", slotUuid.String(), err)
+	}
+
+	displaced, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.SlotReservation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return types.UnavailableAcquireSlotResultType, nil, nil
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get reservation of slot %s: %w", slotUuid.String(), err)
+	}
+
+	if displaced.VehicleUUID == request.VehicleUUID {
+		return types.AcquiredAcquireSlotResultType, nil, nil
+	}
+
+	tag, err := tx.Exec(ctx, "UPDATE vehicles SET current_slot_id = NULL, reserved_until = NULL WHERE id = $1 AND current_slot_id = $2 AND "+holdsLockCondition+";", displaced.VehicleUUID.String(), slotUuid.String(), config.Configuration.GetIdAsString(), term)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return "", nil, errors.New("no rows affected, reservation was not preempted")
+	}
+
+	if err := assignSlot(ctx, tx, slotUuid, request.VehicleUUID, term, reservedUntil); err != nil {
+		return "", nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", nil, err
+	}
+
+	return types.AcquiredAcquireSlotResultType, &displaced, nil
+}
+
+func assignSlot(ctx context.Context, tx pgx.Tx, slotUuid types.UUID, vehicleUuid types.UUID, term int64, reservedUntil time.Time) error {
+	tag, err := tx.Exec(ctx, "UPDATE vehicles SET current_slot_id = $1, reserved_until = $5 WHERE id = $2 AND "+holdsLockCondition+";", slotUuid.String(), vehicleUuid.String(), config.Configuration.GetIdAsString(), term, reservedUntil)
 	if err != nil {
 		return err
 	}
@@ -86,66 +255,327 @@ func (r repository) AcquireSlot(ctx context.Context, vehicleUuid types.UUID, slo
 		return errors.New("no rows affected, slot was not acquired")
 	}
 
+	if _, err := tx.Exec(ctx, "DELETE FROM waitlist WHERE vehicle_id = $1;", vehicleUuid.String()); err != nil {
+		return fmt.Errorf("failed to remove vehicle from waitlist: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE waitlist SET offered_slot_id = NULL, offered_until = NULL WHERE offered_slot_id = $1;", slotUuid.String()); err != nil {
+		return fmt.Errorf("failed to withdraw offer of slot %s: %w", slotUuid.String(), err)
+	}
+
 	return nil
 }
 
-func (r repository) ReleaseSlot(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID) error {
-	tag, err := r.DB.Exec(ctx, "UPDATE vehicles SET current_slot_id = NULL WHERE id = $1 AND current_slot_id = $2;", vehicleUuid.String(), slotUuid.String())
+func isSlotConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == serializationFailureCode || pgErr.Code == uniqueViolationCode
+}
+
+func (r repository) OccupySlot(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID, term int64) error {
+	tag, err := r.DB.Exec(ctx, "UPDATE vehicles SET reserved_until = NULL WHERE id = $1 AND current_slot_id = $2 AND "+holdsLockCondition+";", vehicleUuid.String(), slotUuid.String(), config.Configuration.GetIdAsString(), term)
 	if err != nil {
 		return err
 	}
 
 	if tag.RowsAffected() == 0 {
-		return errors.New("no rows affected, slot was not released")
+		return errors.New("no rows affected, slot was not occupied")
 	}
 
 	return nil
 }
 
-func (r repository) AcquireLock(ctx context.Context) error {
-	tag, err := r.DB.Exec(ctx, "UPDATE tower_lock SET leader_id = $1, renewed_at = NOW() WHERE leader_id = $1 OR leader_id IS NULL OR renewed_at < (NOW() - ($2 || ' seconds')::interval);", config.Configuration.GetIdAsString(), strconv.Itoa(int(config.Configuration.GetRenewLockTimeout().Seconds())))
+// ReleaseSlot offers the slot to the head of its waitlist in the same
+// transaction, so no other vehicle takes it in between.
+func (r repository) ReleaseSlot(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID, term int64, offeredUntil time.Time) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE vehicles SET current_slot_id = NULL, reserved_until = NULL WHERE id = $1 AND current_slot_id = $2 AND "+holdsLockCondition+";", vehicleUuid.String(), slotUuid.String(), config.Configuration.GetIdAsString(), term)
 	if err != nil {
 		return err
 	}
 
 	if tag.RowsAffected() == 0 {
-		return errors.New("no rows affected, lock was not acquired")
+		return errors.New("no rows affected, slot was not released")
+	}
+
+	if _, err := tx.Exec(ctx, offerSlotQuery, slotUuid.String(), offeredUntil); err != nil {
+		return fmt.Errorf("failed to offer slot %s to the waitlist: %w", slotUuid.String(), err)
 	}
 
-	tag, err = r.DB.Exec(ctx, "UPDATE towers SET is_leader = (id = $1);", config.Configuration.GetIdAsString())
+	return tx.Commit(ctx)
+}
+
+func (r repository) EnqueueWaitlist(ctx context.Context, entry types.WaitlistEntry, term int64) (position int, err error) {
+	tag, err := r.DB.Exec(ctx, enqueueWaitlistQuery, entry.VehicleUUID.String(), entry.StructureUUID.String(), entry.StructureType, entry.SlotType, entry.Priority, config.Configuration.GetIdAsString(), term)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if tag.RowsAffected() == 0 {
-		return errors.New("failed to set other towers as non leaders")
+		return 0, errors.New("no rows affected, vehicle was not waitlisted")
 	}
 
-	return nil
+	err = r.DB.QueryRow(ctx, waitlistPositionQuery, entry.VehicleUUID.String()).Scan(&position)
+	return
+}
+
+func (r repository) LeaveWaitlist(ctx context.Context, vehicleUuid types.UUID, term int64) error {
+	_, err := r.DB.Exec(ctx, "DELETE FROM waitlist WHERE vehicle_id = $1 AND EXISTS (SELECT 1 FROM tower_lock WHERE leader_id = $2 AND term = $3);", vehicleUuid.String(), config.Configuration.GetIdAsString(), term)
+	return err
+}
+
+func (r repository) OfferedTo(ctx context.Context, slotUuid types.UUID) (*types.WaitlistEntry, bool, error) {
+	rows, err := r.DB.Query(ctx, offeredToQuery, slotUuid.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.WaitlistEntry])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &entry, true, nil
 }
 
-func (r repository) ReleaseLock(ctx context.Context) error {
-	tag, err := r.DB.Exec(ctx, "UPDATE tower_lock SET leader_id = NULL WHERE leader_id = $1;", config.Configuration.GetIdAsString())
+func (r repository) WithdrawOffer(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID, term int64) error {
+	_, err := r.DB.Exec(ctx, "UPDATE waitlist SET offered_slot_id = NULL, offered_until = NULL WHERE vehicle_id = $1 AND offered_slot_id = $2 AND "+holdsLockCondition+";", vehicleUuid.String(), slotUuid.String(), config.Configuration.GetIdAsString(), term)
+	return err
+}
+
+func (r repository) CreateBooking(ctx context.Context, booking types.Booking, term int64) (types.BookingResultType, *types.Booking, error) {
+	err := r.DB.QueryRow(ctx, createBookingQuery, booking.VehicleUUID.String(), booking.StructureUUID.String(), config.Configuration.GetIdAsString(), term, booking.StartsAt, booking.EndsAt, booking.SlotType, strconv.Itoa(booking.SlotNumber)).Scan(&booking.UUID)
+	if isBookingOverlap(err) {
+		return types.OverlapBookingResultType, nil, nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, errors.New("no rows affected, slot was not booked")
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	booking.Status = types.BookedBookingStatus
+	return types.BookedBookingResultType, &booking, nil
+}
+
+func isBookingOverlap(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == exclusionViolationCode
+}
+
+func (r repository) CancelBooking(ctx context.Context, bookingUuid types.UUID, term int64) error {
+	tag, err := r.DB.Exec(ctx, "UPDATE bookings SET status = 'cancelled' WHERE id = $1 AND status = 'booked' AND EXISTS (SELECT 1 FROM tower_lock WHERE leader_id = $2 AND term = $3);", bookingUuid.String(), config.Configuration.GetIdAsString(), term)
 	if err != nil {
 		return err
 	}
 
 	if tag.RowsAffected() == 0 {
-		return errors.New("no rows affected, lock was not released")
+		return errors.New("no rows affected, booking was not cancelled")
 	}
 
 	return nil
 }
 
-func (r repository) RenewLock(ctx context.Context) error {
-	tag, err := r.DB.Exec(ctx, "UPDATE tower_lock SET leader_id = $1, renewed_at = NOW() WHERE leader_id = $1;", config.Configuration.GetIdAsString())
+func (r repository) ListBookings(ctx context.Context, structureUuid types.UUID, from time.Time, to *time.Time) ([]types.Booking, error) {
+	rows, err := r.DB.Query(ctx, listBookingsQuery+" WHERE sl.structure_id = $1 AND b.ends_at > $2 AND ($3::timestamptz IS NULL OR b.starts_at < $3) ORDER BY b.starts_at, sl.type, sl.number;", structureUuid.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[types.Booking])
+}
+
+func (r repository) GetActiveBooking(ctx context.Context, vehicleUuid types.UUID, slotUuid types.UUID) (*types.Booking, bool, error) {
+	rows, err := r.DB.Query(ctx, listBookingsQuery+" WHERE b.slot_id = $1 AND b.vehicle_id = $2 AND b.status = 'booked' AND b.starts_at <= NOW() AND b.ends_at > NOW();", slotUuid.String(), vehicleUuid.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	booking, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Booking])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &booking, true, nil
+}
+
+func (r repository) FulfilBooking(ctx context.Context, booking types.Booking, slotUuid types.UUID, term int64) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE bookings SET status = 'fulfilled' WHERE id = $1 AND slot_id = $2 AND status = 'booked' AND "+holdsLockCondition+";", booking.UUID.String(), slotUuid.String(), config.Configuration.GetIdAsString(), term)
 	if err != nil {
 		return err
 	}
 
 	if tag.RowsAffected() == 0 {
-		return errors.New("no rows affected, lock was not renewed")
+		return errors.New("no rows affected, booking was not fulfilled")
 	}
 
-	return nil
+	tag, err = tx.Exec(ctx, "UPDATE vehicles SET current_slot_id = $1, reserved_until = NULL WHERE id = $2 AND "+holdsLockCondition+";", slotUuid.String(), booking.VehicleUUID.String(), config.Configuration.GetIdAsString(), term)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.New("no rows affected, slot was not occupied")
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM waitlist WHERE vehicle_id = $1;", booking.VehicleUUID.String()); err != nil {
+		return fmt.Errorf("failed to remove vehicle from waitlist: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r repository) ListHeldSlots(ctx context.Context, structureUuid types.UUID) ([]types.SlotReservation, error) {
+	rows, err := r.DB.Query(ctx, heldSlotsQuery, structureUuid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[types.SlotReservation])
+}
+
+func (r repository) RecordTransition(ctx context.Context, entry types.SlotHistoryEntry, term int64) error {
+	_, err := r.DB.Exec(ctx, recordTransitionQuery, entry.VehicleUUID.String(), entry.StructureUUID.String(), entry.SlotType, entry.SlotNumber, entry.Transition, term)
+	return err
+}
+
+func (r repository) ListSlotVisits(ctx context.Context, structureUuid types.UUID, from time.Time, to time.Time) ([]types.SlotVisit, error) {
+	rows, err := r.DB.Query(ctx, slotVisitsQuery, structureUuid.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[types.SlotVisit])
+}
+
+func (r repository) CountSlots(ctx context.Context, structureUuid types.UUID) (map[types.SlotType]int, error) {
+	rows, err := r.DB.Query(ctx, "SELECT type::text, COUNT(*) FROM slots WHERE structure_id = $1 GROUP BY type;", structureUuid.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := make(map[types.SlotType]int)
+	for rows.Next() {
+		var slotType types.SlotType
+		var count int
+		if err := rows.Scan(&slotType, &count); err != nil {
+			return nil, err
+		}
+
+		counts[slotType] = count
+	}
+
+	return counts, rows.Err()
+}
+
+type expiredReservation struct {
+	types.SlotReservation
+	SlotUUID types.UUID `db:"slot_id"`
+}
+
+func (r repository) ExpireReservations(ctx context.Context, term int64, offeredUntil time.Time) ([]types.SlotReservation, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, expireOffersQuery); err != nil {
+		return nil, fmt.Errorf("failed to expire waitlist offers: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, expireReservationsQuery, config.Configuration.GetIdAsString(), term)
+	if err != nil {
+		return nil, err
+	}
+
+	expired, err := pgx.CollectRows(rows, pgx.RowToStructByName[expiredReservation])
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := make([]types.SlotReservation, 0, len(expired))
+	for _, reservation := range expired {
+		if _, err := tx.Exec(ctx, offerSlotQuery, reservation.SlotUUID.String(), offeredUntil); err != nil {
+			return nil, fmt.Errorf("failed to offer slot %s to the waitlist: %w", reservation.SlotUUID.String(), err)
+		}
+
+		reservations = append(reservations, reservation.SlotReservation)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (r repository) GetIdempotentResult(ctx context.Context, key string, retention time.Duration) (*idempotency.Result, bool, error) {
+	var result idempotency.Result
+	err := r.DB.QueryRow(ctx, "SELECT status_code, content_type, body, request_hash, pending FROM idempotency_keys WHERE key = $1 AND created_at >= (NOW() - ($2 || ' seconds')::interval);", key, strconv.Itoa(int(retention.Seconds()))).Scan(&result.StatusCode, &result.ContentType, &result.Body, &result.RequestHash, &result.Pending)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &result, true, nil
+}
+
+// ClaimIdempotencyKey takes over rows past the retention window and claims
+// older than claimTimeout, and reports whether the key was claimed.
+func (r repository) ClaimIdempotencyKey(ctx context.Context, key string, requestHash string, retention time.Duration, claimTimeout time.Duration) (bool, error) {
+	tag, err := r.DB.Exec(ctx, "INSERT INTO idempotency_keys (key, status_code, content_type, body, request_hash, pending) VALUES ($1, 0, '', '', $2, TRUE) ON CONFLICT (key) DO UPDATE SET status_code = 0, content_type = '', body = '', request_hash = EXCLUDED.request_hash, pending = TRUE, created_at = NOW() WHERE idempotency_keys.created_at < (NOW() - ($3 || ' seconds')::interval) OR (idempotency_keys.pending AND idempotency_keys.created_at < (NOW() - ($4 || ' seconds')::interval));", key, requestHash, strconv.Itoa(int(retention.Seconds())), strconv.Itoa(int(claimTimeout.Seconds())))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r repository) PutIdempotentResult(ctx context.Context, key string, result idempotency.Result) error {
+	_, err := r.DB.Exec(ctx, "INSERT INTO idempotency_keys (key, status_code, content_type, body, request_hash) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (key) DO UPDATE SET status_code = EXCLUDED.status_code, content_type = EXCLUDED.content_type, body = EXCLUDED.body, request_hash = EXCLUDED.request_hash, pending = FALSE, created_at = NOW();", key, result.StatusCode, result.ContentType, result.Body, result.RequestHash)
+	return err
+}
+
+func (r repository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := r.DB.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND pending;", key)
+	return err
+}
+
+func (r repository) DeleteExpiredIdempotentResults(ctx context.Context, retention time.Duration) error {
+	_, err := r.DB.Exec(ctx, "DELETE FROM idempotency_keys WHERE created_at < (NOW() - ($1 || ' seconds')::interval);", strconv.Itoa(int(retention.Seconds())))
+	return err
 }
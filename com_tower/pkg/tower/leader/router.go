@@ -1,21 +1,55 @@
 package leader
 
 import (
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/gin-gonic/gin"
 )
 
-func setupRouter(svc service) (router *gin.Engine) {
+func setupRouter(svc service, results idempotency.Store) (router *gin.Engine) {
 	handler := newHandler(svc)
+	idempotent := idempotency.Middleware(results)
 
 	router = gin.Default()
 	router.GET("towers", handler.ListHealthyTowers)
 	router.GET("towers/", handler.ListHealthyTowers)
-	router.POST("tower-health", handler.MarkTowerAsAlive)
-	router.POST("tower-health/", handler.MarkTowerAsAlive)
-	router.POST("acquire-slot", handler.AcquireSlot)
-	router.POST("acquire-slot/", handler.AcquireSlot)
-	router.POST("release-slot", handler.ReleaseSlot)
-	router.POST("release-slot/", handler.ReleaseSlot)
+	router.GET("state", handler.GetStateSnapshot)
+	router.GET("state/", handler.GetStateSnapshot)
+	router.GET("cluster", handler.GetClusterView)
+	router.GET("cluster/", handler.GetClusterView)
+	router.GET("reconciliation", handler.GetReconciliation)
+	router.GET("reconciliation/", handler.GetReconciliation)
+	router.GET("analytics/utilization", handler.GetUtilization)
+	router.GET("analytics/utilization/", handler.GetUtilization)
+	router.GET("analytics/dwell-times", handler.GetDwellTimes)
+	router.GET("analytics/dwell-times/", handler.GetDwellTimes)
+	router.GET("analytics/turnaround", handler.GetTurnaround)
+	router.GET("analytics/turnaround/", handler.GetTurnaround)
+	router.POST("tower-health", RequireCurrentTerm(), handler.MarkTowerAsAlive)
+	router.POST("tower-health/", RequireCurrentTerm(), handler.MarkTowerAsAlive)
+	router.POST("free-slots", RequireCurrentTerm(), handler.ListFreeSlots)
+	router.POST("free-slots/", RequireCurrentTerm(), handler.ListFreeSlots)
+	router.POST("acquire-slot", RequireCurrentTerm(), idempotent, handler.AcquireSlot)
+	router.POST("acquire-slot/", RequireCurrentTerm(), idempotent, handler.AcquireSlot)
+	router.POST("preempt-slot", RequireCurrentTerm(), handler.PreemptSlot)
+	router.POST("preempt-slot/", RequireCurrentTerm(), handler.PreemptSlot)
+	router.POST("occupy-slot", RequireCurrentTerm(), idempotent, handler.OccupySlot)
+	router.POST("occupy-slot/", RequireCurrentTerm(), idempotent, handler.OccupySlot)
+	router.POST("release-slot", RequireCurrentTerm(), idempotent, handler.ReleaseSlot)
+	router.POST("release-slot/", RequireCurrentTerm(), idempotent, handler.ReleaseSlot)
+	router.POST("waitlist", RequireCurrentTerm(), handler.EnqueueWaitlist)
+	router.POST("waitlist/", RequireCurrentTerm(), handler.EnqueueWaitlist)
+	router.POST("waitlist/leave", RequireCurrentTerm(), handler.LeaveWaitlist)
+	router.POST("waitlist/leave/", RequireCurrentTerm(), handler.LeaveWaitlist)
+	router.GET("bookings", RequireCurrentTerm(), handler.ListBookings)
+	router.GET("bookings/", RequireCurrentTerm(), handler.ListBookings)
+	router.POST("bookings", RequireCurrentTerm(), handler.CreateBooking)
+	router.POST("bookings/", RequireCurrentTerm(), handler.CreateBooking)
+	router.POST("bookings/cancel", RequireCurrentTerm(), handler.CancelBooking)
+	router.POST("bookings/cancel/", RequireCurrentTerm(), handler.CancelBooking)
+	router.POST("pre-vote", handler.HandlePreVote)
+	router.POST("pre-vote/", handler.HandlePreVote)
+	router.POST("transfer-leadership", RequireAdminToken(), handler.TransferLeadership)
+	router.POST("transfer-leadership/", RequireAdminToken(), handler.TransferLeadership)
 
 	return
 }
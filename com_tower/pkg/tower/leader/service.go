@@ -3,34 +3,94 @@ package leader
 import (
 	"context"
 	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
 
 type service struct {
-	repository repository
+	// canceled when the tower stops leading
+	lifecycle   context.Context
+	background  *sync.WaitGroup
+	repository  repository
+	integration integration
+	elector     leaderelection.Elector
+	lease       *lease
+	slots       *slotGate
+	quorum      *quorum
+	turnover    *turnover
+	reconciler  *reconciler
+	state       *clusterState
+	propagation *propagationHealth
 }
 
-func newService(r repository) service {
+func newService(ctx context.Context, r repository, i integration, e leaderelection.Elector) service {
 	return service{
-		repository: r,
+		lifecycle:   ctx,
+		background:  &sync.WaitGroup{},
+		repository:  r,
+		integration: i,
+		elector:     e,
+		lease:       newLease(),
+		slots:       newSlotGate(),
+		quorum:      newQuorum(),
+		turnover:    newTurnover(),
+		reconciler:  newReconciler(),
+		state:       newClusterState(),
+		propagation: newPropagationHealth(),
 	}
 }
 
 func (s service) AcquireLock(ctx context.Context) error {
-	return s.repository.AcquireLock(ctx)
+	requestedAt := time.Now()
+	term, err := s.elector.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !consensus.Enabled() {
+		s.lease.Renew(requestedAt)
+	}
+
+	config.Configuration.SetLeader(config.Configuration.GetId(), term)
+	return nil
 }
 
 func (s service) ReleaseLock(ctx context.Context) error {
-	return s.repository.ReleaseLock(ctx)
+	return s.elector.Release(ctx, config.Configuration.GetLeaderTerm())
 }
 
 func (s service) RenewLock(ctx context.Context) error {
-	return s.repository.RenewLock(ctx)
+	requestedAt := time.Now()
+	if err := s.elector.Renew(ctx, config.Configuration.GetLeaderTerm()); err != nil {
+		return err
+	}
+
+	s.lease.Renew(requestedAt)
+	return nil
+}
+
+func (s service) Resign(ctx context.Context) error {
+	return s.elector.Resign(ctx)
+}
+
+func (s service) IsLeaseExpired() bool {
+	return s.lease.Expired()
 }
 
-func (s service) MarkTowerAsAlive(ctx context.Context, id types.UUID) (err error) {
+func (s service) RevokeLease() {
+	s.lease.Revoke()
+}
+
+func (s service) MarkTowerAsAlive(ctx context.Context, request types.TowerHealthRequest) (err error) {
+	id := request.Id
 	if _, err := s.repository.GetTowerById(ctx, id); err != nil {
 		return fmt.Errorf("failed to check if tower exists: %w", err)
 	}
@@ -39,10 +99,60 @@ func (s service) MarkTowerAsAlive(ctx context.Context, id types.UUID) (err error
 		return fmt.Errorf("failed to mark tower as alive: %w", err)
 	}
 
+	s.quorum.Seen(id)
+	s.state.Reported(id, request.TowersVersion, request.StructuresVersion)
+
 	return
 }
 
+func (s service) GetStateVersion() *types.StateVersion {
+	return s.state.Version()
+}
+
+func (s service) GetStateSnapshot(ctx context.Context) (*types.StateSnapshot, error) {
+	towers, err := s.ListHealthyTowers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list healthy towers: %w", err)
+	}
+
+	structures, err := s.ListStructures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.state.Update(towers, *structures)
+	snapshot := s.state.Snapshot()
+	return &snapshot, nil
+}
+
+func (s service) ListTowers(ctx context.Context) ([]types.Tower, error) {
+	return s.repository.ListTowers(ctx)
+}
+
+func (s service) CheckQuorum(ctx context.Context) error {
+	towers, err := s.repository.ListTowers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list towers: %w", err)
+	}
+
+	wasReadOnly := s.quorum.ReadOnly()
+	alive, size := s.quorum.Evaluate(towers)
+	if readOnly := s.quorum.ReadOnly(); readOnly != wasReadOnly {
+		if readOnly {
+			log.Printf("[leader][quorum] only %d of %d towers alive, quorum is %d: entering read-only mode", alive, len(towers), size)
+		} else {
+			log.Printf("[leader][quorum] %d of %d towers alive, quorum is %d: leaving read-only mode", alive, len(towers), size)
+		}
+	}
+
+	return nil
+}
+
 func (s service) ListHealthyTowers(ctx context.Context) (towers []types.Tower, err error) {
+	if consensus.Enabled() {
+		return consensus.Replica.ListTowers(), nil
+	}
+
 	towers, err = s.repository.ListTowersByLastSeenAt(ctx, int(config.Configuration.GetHeartbeatTimeout().Seconds()))
 	if err != nil {
 		return nil, err
@@ -51,6 +161,25 @@ func (s service) ListHealthyTowers(ctx context.Context) (towers []types.Tower, e
 	return
 }
 
+func (s service) ListTowerStatuses(ctx context.Context) ([]types.TowerStatus, error) {
+	towers, err := s.ListHealthyTowers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]types.TowerStatus, 0, len(towers))
+	for _, tower := range towers {
+		status := types.TowerStatus{Tower: tower}
+		if health, ok := s.propagation.Get(tower.UUID); ok {
+			status.Propagation = &health
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
 func (s service) ListStructures(ctx context.Context) (*types.Structures, error) {
 	platforms, err := s.repository.ListPlatforms(ctx)
 	if err != nil {
@@ -68,41 +197,816 @@ func (s service) ListStructures(ctx context.Context) (*types.Structures, error)
 	}, nil
 }
 
+func (s service) ListFreeSlots(ctx context.Context, request types.FreeSlotsRequest) (*types.FreeSlotsResponse, error) {
+	if consensus.Enabled() {
+		return &types.FreeSlotsResponse{SlotNumbers: consensus.Replica.ListFreeSlots(request.StructureUUID, request.SlotType)}, nil
+	}
+
+	slotNumbers, err := s.repository.ListFreeSlots(ctx, request.StructureUUID, request.SlotType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list free slots: %w", err)
+	}
+
+	return &types.FreeSlotsResponse{SlotNumbers: slotNumbers}, nil
+}
+
 func (s service) AcquireSlot(ctx context.Context, request types.AcquireSlotRequest) (*types.AcquireSlotResponse, error) {
+	if !s.slots.Enter() {
+		return nil, utils.ErrTransferInProgress
+	}
+	defer s.slots.Leave()
+
+	reservedUntil := time.Now().Add(config.Configuration.GetReservationTTL())
+
+	var response *types.AcquireSlotResponse
+	if consensus.Enabled() {
+		var err error
+		response, err = consensus.Replica.AcquireSlot(request, reservedUntil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if s.lease.Expired() {
+			return nil, utils.ErrLeaseExpired
+		}
+
+		if s.quorum.ReadOnly() {
+			return nil, utils.ErrNoQuorum
+		}
+
+		result, err := s.repository.AcquireSlot(ctx, request, config.Configuration.GetLeaderTerm(), reservedUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire %s %d in structure %s: %w", request.SlotType, request.SlotNumber, request.StructureUUID.String(), err)
+		}
+
+		response = &types.AcquireSlotResponse{Result: result}
+	}
+
+	if response.Result == types.AcquiredAcquireSlotResultType {
+		s.recordTransition(ctx, request, types.AcquiredSlotTransition)
+	}
+
+	return response, nil
+}
+
+func (s service) PreemptSlot(ctx context.Context, request types.AcquireSlotRequest) (*types.AcquireSlotResponse, error) {
+	if request.Priority != types.EmergencySlotPriority {
+		return nil, fmt.Errorf("only emergency requests can preempt slots: %w", utils.ErrInvalidInput)
+	}
+
+	if !s.slots.Enter() {
+		return nil, utils.ErrTransferInProgress
+	}
+	defer s.slots.Leave()
+
+	reservedUntil := time.Now().Add(config.Configuration.GetReservationTTL())
+
+	var response *types.AcquireSlotResponse
+	var displaced *types.SlotReservation
+	if consensus.Enabled() {
+		var err error
+		response, displaced, err = consensus.Replica.PreemptSlot(request, reservedUntil)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if s.lease.Expired() {
+			return nil, utils.ErrLeaseExpired
+		}
+
+		if s.quorum.ReadOnly() {
+			return nil, utils.ErrNoQuorum
+		}
+
+		result, preempted, err := s.repository.PreemptSlot(ctx, request, config.Configuration.GetLeaderTerm(), reservedUntil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preempt %s %d in structure %s: %w", request.SlotType, request.SlotNumber, request.StructureUUID.String(), err)
+		}
+
+		response = &types.AcquireSlotResponse{Result: result}
+		displaced = preempted
+	}
+
+	if displaced != nil {
+		s.recordTransition(ctx, types.AcquireSlotRequest{VehicleUUID: displaced.VehicleUUID, StructureUUID: displaced.StructureUUID, StructureSlotRequest: types.StructureSlotRequest{SlotNumber: displaced.SlotNumber, SlotType: displaced.SlotType}}, types.ReleasedSlotTransition)
+		s.notifyPreempted(ctx, *displaced, request.VehicleUUID)
+	}
+
+	if response.Result == types.AcquiredAcquireSlotResultType {
+		s.recordTransition(ctx, request, types.AcquiredSlotTransition)
+	}
+
+	return response, nil
+}
+
+// notifyPreempted only logs failures: the slot already changed hands.
+func (s service) notifyPreempted(ctx context.Context, displaced types.SlotReservation, preemptedBy types.UUID) {
+	log.Printf("[leader][preemption] %s %d in %s %s reserved for vehicle %s was preempted by emergency vehicle %s", displaced.SlotType, displaced.SlotNumber, displaced.StructureType, displaced.StructureUUID.String(), displaced.VehicleUUID.String(), preemptedBy.String())
+
+	now := int(time.Now().Unix())
+	preemption := types.PreemptionMessage{
+		VehicleUUID:   displaced.VehicleUUID,
+		StructureUUID: displaced.StructureUUID,
+		StructureType: displaced.StructureType,
+		SlotType:      displaced.SlotType,
+		SlotNumber:    displaced.SlotNumber,
+		PreemptedBy:   preemptedBy,
+		Timestamp:     now,
+	}
+
+	if err := s.integration.PublishPreemption(ctx, preemption); err != nil {
+		log.Printf("[leader][preemption] failed to notify vehicle %s: %v", displaced.VehicleUUID.String(), err)
+	}
+
+	audit := types.AuditRequest{
+		VehicleType:   types.GetVehicleTypeBySlotType(displaced.SlotType),
+		VehicleUUID:   displaced.VehicleUUID,
+		StructureType: displaced.StructureType,
+		StructureUUID: displaced.StructureUUID,
+		Timestamp:     now,
+		Result:        types.PreemptedResultType,
+		SlotNumber:    displaced.SlotNumber,
+		TowerUUID:     config.Configuration.GetId(),
+	}
+
+	if err := s.integration.PublishAudit(ctx, audit); err != nil {
+		log.Printf("[leader][preemption] failed to audit preemption of vehicle %s: %v", displaced.VehicleUUID.String(), err)
+	}
+}
+
+func (s service) OccupySlot(ctx context.Context, request types.OccupySlotRequest) error {
+	if consensus.Enabled() {
+		if err := consensus.Replica.OccupySlot(request); err != nil {
+			return err
+		}
+
+		s.recordTransition(ctx, types.AcquireSlotRequest(request), types.ArrivedSlotTransition)
+		return nil
+	}
+
 	slotUuid, err := s.repository.GetSlotUUID(ctx, request.StructureUUID, request.SlotType, request.SlotNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get slot uuid: %w", err)
+		return fmt.Errorf("failed to get slot uuid: %w", err)
 	}
 
-	isSlotAvailable, err := s.repository.CheckSlotAvailability(ctx, slotUuid)
+	booking, found, err := s.repository.GetActiveBooking(ctx, request.VehicleUUID, slotUuid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check slot %s availability: %w", slotUuid.String(), err)
+		return fmt.Errorf("failed to get booking of slot %s: %w", slotUuid.String(), err)
 	}
 
-	if !isSlotAvailable {
-		return &types.AcquireSlotResponse{
-			Result: types.UnavailableAcquireSlotResultType,
-		}, nil
+	if found {
+		return s.fulfilBooking(ctx, *booking, slotUuid)
 	}
 
-	if err := s.repository.AcquireSlot(ctx, request.VehicleUUID, slotUuid); err != nil {
-		return nil, fmt.Errorf("failed to acquire slot %s: %w", slotUuid.String(), err)
+	if err := s.repository.OccupySlot(ctx, request.VehicleUUID, slotUuid, config.Configuration.GetLeaderTerm()); err != nil {
+		return fmt.Errorf("failed to occupy slot %s: %w", slotUuid.String(), err)
 	}
 
-	return &types.AcquireSlotResponse{
-		Result: types.AcquiredAcquireSlotResultType,
-	}, nil 
+	s.recordTransition(ctx, types.AcquireSlotRequest(request), types.ArrivedSlotTransition)
+	return nil
+}
+
+func (s service) fulfilBooking(ctx context.Context, booking types.Booking, slotUuid types.UUID) error {
+	slot := types.StructureSlotRequest{SlotNumber: booking.SlotNumber, SlotType: booking.SlotType}
+	structureResp, err := s.integration.RequestSlotToStructure(ctx, booking.StructureUUID, booking.StructureType, slot)
+	if err != nil {
+		return fmt.Errorf("failed to request booked %s %d to %s %s: %w", slot.SlotType, slot.SlotNumber, booking.StructureType, booking.StructureUUID.String(), err)
+	}
+
+	if structureResp.State != types.FreeSlotState {
+		return fmt.Errorf("booked %s %d in %s %s is taken", slot.SlotType, slot.SlotNumber, booking.StructureType, booking.StructureUUID.String())
+	}
+
+	if err := s.repository.FulfilBooking(ctx, booking, slotUuid, config.Configuration.GetLeaderTerm()); err != nil {
+		releaseReq := types.ReleaseSlotRequest(slot)
+		if err := s.integration.ReleaseSlot(ctx, booking.StructureUUID, booking.StructureType, releaseReq); err != nil {
+			log.Printf("[leader][bookings] failed to rollback slot request in %s %s: %v", booking.StructureType, booking.StructureUUID.String(), err)
+		}
+
+		return fmt.Errorf("failed to fulfil booking %s: %w", booking.UUID.String(), err)
+	}
+
+	log.Printf("[leader][bookings] vehicle %s arrived at %s %d in structure %s booked in %s", booking.VehicleUUID.String(), slot.SlotType, slot.SlotNumber, booking.StructureUUID.String(), booking.UUID.String())
+
+	// the booked slot is only taken when the vehicle arrives
+	visit := types.AcquireSlotRequest{VehicleUUID: booking.VehicleUUID, StructureUUID: booking.StructureUUID, StructureSlotRequest: slot}
+	s.recordTransition(ctx, visit, types.AcquiredSlotTransition)
+	s.recordTransition(ctx, visit, types.ArrivedSlotTransition)
+	return nil
+}
+
+// CreateBooking keeps bookings in Postgres only, fenced by the tower lock.
+func (s service) CreateBooking(ctx context.Context, request types.BookingRequest) (*types.BookingResponse, error) {
+	if consensus.Enabled() {
+		return nil, fmt.Errorf("bookings are kept in the tower lock database: %w", utils.ErrRaftUnsupported)
+	}
+
+	slotType := types.GetSlotTypeByVehicleType(request.VehicleType)
+	if slotType == "" {
+		return nil, fmt.Errorf("unknown vehicle type %s: %w", request.VehicleType, utils.ErrInvalidInput)
+	}
+
+	if !request.StartsAt.Before(request.EndsAt) || !request.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("booking window must end after it starts and in the future: %w", utils.ErrInvalidInput)
+	}
+
+	if s.lease.Expired() {
+		return nil, utils.ErrLeaseExpired
+	}
+
+	booking := types.Booking{
+		VehicleUUID:   request.VehicleUUID,
+		StructureUUID: request.StructureUUID,
+		StructureType: request.StructureType,
+		SlotType:      slotType,
+		SlotNumber:    request.SlotNumber,
+		StartsAt:      request.StartsAt,
+		EndsAt:        request.EndsAt,
+	}
+
+	result, created, err := s.repository.CreateBooking(ctx, booking, config.Configuration.GetLeaderTerm())
+	if err != nil {
+		return nil, fmt.Errorf("failed to book %s %d in structure %s: %w", slotType, request.SlotNumber, request.StructureUUID.String(), err)
+	}
+
+	return &types.BookingResponse{
+		Result:  result,
+		Booking: created,
+	}, nil
+}
+
+func (s service) CancelBooking(ctx context.Context, request types.CancelBookingRequest) error {
+	if consensus.Enabled() {
+		return fmt.Errorf("bookings are kept in the tower lock database: %w", utils.ErrRaftUnsupported)
+	}
+
+	if s.lease.Expired() {
+		return utils.ErrLeaseExpired
+	}
+
+	if err := s.repository.CancelBooking(ctx, request.BookingUUID, config.Configuration.GetLeaderTerm()); err != nil {
+		return fmt.Errorf("failed to cancel booking %s: %w", request.BookingUUID.String(), err)
+	}
+
+	return nil
+}
+
+func (s service) ListBookings(ctx context.Context, structureUuid types.UUID, from time.Time, to *time.Time) (*types.BookingsResponse, error) {
+	if consensus.Enabled() {
+		return nil, fmt.Errorf("bookings are kept in the tower lock database: %w", utils.ErrRaftUnsupported)
+	}
+
+	bookings, err := s.repository.ListBookings(ctx, structureUuid, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookings of structure %s: %w", structureUuid.String(), err)
+	}
+
+	return &types.BookingsResponse{Bookings: bookings}, nil
 }
 
 func (s service) ReleaseSlot(ctx context.Context, request types.ReleaseSlotLockRequest) error {
+	if err := s.releaseSlotLock(ctx, request); err != nil {
+		return err
+	}
+
+	s.recordTransition(ctx, types.AcquireSlotRequest(request), types.DepartedSlotTransition)
+
+	s.turnover.Released(request.StructureUUID, request.SlotType)
+
+	term := config.Configuration.GetLeaderTerm()
+	s.background.Go(func() {
+		s.GrantWaitlisted(s.lifecycle, term, request.StructureUUID, request.StructureSlotRequest)
+	})
+	return nil
+}
+
+func (s service) Wait() {
+	s.background.Wait()
+}
+
+func (s service) releaseSlotLock(ctx context.Context, request types.ReleaseSlotLockRequest) error {
+	offeredUntil := time.Now().Add(config.Configuration.GetReservationTTL())
+	if consensus.Enabled() {
+		return consensus.Replica.ReleaseSlot(request, offeredUntil)
+	}
+
 	slotUuid, err := s.repository.GetSlotUUID(ctx, request.StructureUUID, request.SlotType, request.SlotNumber)
 	if err != nil {
 		return fmt.Errorf("failed to get slot uuid: %w", err)
 	}
 
-	if err := s.repository.ReleaseSlot(ctx, request.VehicleUUID, slotUuid); err != nil {
+	if err := s.repository.ReleaseSlot(ctx, request.VehicleUUID, slotUuid, config.Configuration.GetLeaderTerm(), offeredUntil); err != nil {
 		return fmt.Errorf("failed to release slot %s: %w", slotUuid.String(), err)
 	}
 
 	return nil 
 }
+
+func (s service) ExpireIdempotentResults(ctx context.Context) error {
+	if consensus.Enabled() {
+		return nil
+	}
+
+	if err := s.repository.DeleteExpiredIdempotentResults(ctx, config.Configuration.GetIdempotencyTTL()); err != nil {
+		return fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return nil
+}
+
+func (s service) ExpireReservations(ctx context.Context) error {
+	term := config.Configuration.GetLeaderTerm()
+	offeredUntil := time.Now().Add(config.Configuration.GetReservationTTL())
+
+	var expired []types.SlotReservation
+	var err error
+	if consensus.Enabled() {
+		expired, err = consensus.Replica.ExpireReservations(time.Now(), offeredUntil)
+	} else {
+		expired, err = s.repository.ExpireReservations(ctx, term, offeredUntil)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	for _, reservation := range expired {
+		log.Printf("[leader][reservations] reservation of %s %d in %s %s for vehicle %s expired", reservation.SlotType, reservation.SlotNumber, reservation.StructureType, reservation.StructureUUID.String(), reservation.VehicleUUID.String())
+		s.recordTransition(ctx, types.AcquireSlotRequest{VehicleUUID: reservation.VehicleUUID, StructureUUID: reservation.StructureUUID, StructureSlotRequest: types.StructureSlotRequest{SlotNumber: reservation.SlotNumber, SlotType: reservation.SlotType}}, types.ReleasedSlotTransition)
+
+		releaseReq := types.ReleaseSlotRequest{SlotNumber: reservation.SlotNumber, SlotType: reservation.SlotType}
+		if err := s.integration.ReleaseSlot(ctx, reservation.StructureUUID, reservation.StructureType, releaseReq); err != nil {
+			log.Printf("[leader][reservations] failed to release expired slot in %s %s: %v", reservation.StructureType, reservation.StructureUUID.String(), err)
+			continue
+		}
+
+		s.turnover.Released(reservation.StructureUUID, reservation.SlotType)
+		s.GrantWaitlisted(ctx, term, reservation.StructureUUID, types.StructureSlotRequest{SlotNumber: reservation.SlotNumber, SlotType: reservation.SlotType})
+	}
+
+	return nil
+}
+
+// Reconcile repairs towards the leader's view, which vehicles were granted
+// slots from.
+func (s service) Reconcile(ctx context.Context) error {
+	if !consensus.Enabled() && s.IsLeaseExpired() {
+		return nil
+	}
+
+	var structures types.Structures
+	if consensus.Enabled() {
+		structures = consensus.Replica.ListStructures()
+	} else {
+		listed, err := s.ListStructures(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list structures: %w", err)
+		}
+
+		structures = *listed
+	}
+
+	found := []types.Divergence{}
+	for _, platform := range structures.Platforms {
+		divergences, err := s.diffStructure(ctx, platform.UUID, types.PlatformStructureType)
+		if err != nil {
+			log.Printf("[leader][reconcile] failed to reconcile %s %s: %v", types.PlatformStructureType, platform.UUID.String(), err)
+			s.reconciler.StructureFailed()
+			continue
+		}
+
+		found = append(found, divergences...)
+	}
+
+	for _, central := range structures.Centrals {
+		divergences, err := s.diffStructure(ctx, central.UUID, types.CentralStructureType)
+		if err != nil {
+			log.Printf("[leader][reconcile] failed to reconcile %s %s: %v", types.CentralStructureType, central.UUID.String(), err)
+			s.reconciler.StructureFailed()
+			continue
+		}
+
+		found = append(found, divergences...)
+	}
+
+	detected, confirmed := s.reconciler.Record(found)
+	for _, divergence := range detected {
+		log.Printf("[leader][reconcile] %s %d in %s %s diverges: %s", divergence.SlotType, divergence.SlotNumber, divergence.StructureType, divergence.StructureUUID.String(), divergence.Type)
+		s.auditDivergence(ctx, divergence, types.DivergedResultType)
+	}
+
+	if config.Configuration.GetReconcilePolicy() != types.RepairReconcilePolicy {
+		return nil
+	}
+
+	for _, divergence := range confirmed {
+		if err := s.repairDivergence(ctx, divergence); err != nil {
+			log.Printf("[leader][reconcile] failed to repair %s %d in %s %s: %v", divergence.SlotType, divergence.SlotNumber, divergence.StructureType, divergence.StructureUUID.String(), err)
+			s.reconciler.Repaired(false)
+			continue
+		}
+
+		log.Printf("[leader][reconcile] repaired %s %d in %s %s: %s", divergence.SlotType, divergence.SlotNumber, divergence.StructureType, divergence.StructureUUID.String(), divergence.Type)
+		s.reconciler.Repaired(true)
+		s.auditDivergence(ctx, divergence, types.RepairedResultType)
+	}
+
+	return nil
+}
+
+func (s service) diffStructure(ctx context.Context, structureUuid types.UUID, structureType types.StructureType) ([]types.Divergence, error) {
+	var held []types.SlotReservation
+	if consensus.Enabled() {
+		held = consensus.Replica.ListHeldSlots(structureUuid)
+	} else {
+		var err error
+		held, err = s.repository.ListHeldSlots(ctx, structureUuid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list held slots: %w", err)
+		}
+	}
+
+	slots, err := s.integration.ListStructureSlots(ctx, structureUuid, structureType)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make(map[types.StructureSlotRequest]types.UUID, len(held))
+	for _, slot := range held {
+		holders[types.StructureSlotRequest{SlotNumber: slot.SlotNumber, SlotType: slot.SlotType}] = slot.VehicleUUID
+	}
+
+	divergences := []types.Divergence{}
+	for _, slot := range slots.Slots {
+		divergence := types.Divergence{
+			StructureUUID: structureUuid,
+			StructureType: structureType,
+			SlotType:      slot.SlotType,
+			SlotNumber:    slot.SlotNumber,
+		}
+
+		holder, isHeld := holders[types.StructureSlotRequest{SlotNumber: slot.SlotNumber, SlotType: slot.SlotType}]
+		switch {
+		case slot.State == types.FreeSlotState && isHeld:
+			divergence.Type = types.FreeInStructureDivergenceType
+			divergence.VehicleUUID = &holder
+		case slot.State == types.InUseSlotState && !isHeld:
+			divergence.Type = types.UnheldInLeaderDivergenceType
+		default:
+			continue
+		}
+
+		divergences = append(divergences, divergence)
+	}
+
+	return divergences, nil
+}
+
+func (s service) repairDivergence(ctx context.Context, divergence types.Divergence) error {
+	slot := types.StructureSlotRequest{SlotNumber: divergence.SlotNumber, SlotType: divergence.SlotType}
+	switch divergence.Type {
+	case types.FreeInStructureDivergenceType:
+		_, err := s.integration.RequestSlotToStructure(ctx, divergence.StructureUUID, divergence.StructureType, slot)
+		return err
+	case types.UnheldInLeaderDivergenceType:
+		return s.integration.ReleaseSlot(ctx, divergence.StructureUUID, divergence.StructureType, types.ReleaseSlotRequest(slot))
+	default:
+		return fmt.Errorf("unknown divergence type %s", divergence.Type)
+	}
+}
+
+func (s service) auditDivergence(ctx context.Context, divergence types.Divergence, result types.ResultType) {
+	audit := types.AuditRequest{
+		VehicleType:   types.GetVehicleTypeBySlotType(divergence.SlotType),
+		StructureType: divergence.StructureType,
+		StructureUUID: divergence.StructureUUID,
+		Timestamp:     int(time.Now().Unix()),
+		Result:        result,
+		SlotNumber:    divergence.SlotNumber,
+		TowerUUID:     config.Configuration.GetId(),
+	}
+
+	if divergence.VehicleUUID != nil {
+		audit.VehicleUUID = *divergence.VehicleUUID
+	}
+
+	if err := s.integration.PublishAudit(ctx, audit); err != nil {
+		log.Printf("[leader][reconcile] failed to audit divergence of %s %d in %s %s: %v", divergence.SlotType, divergence.SlotNumber, divergence.StructureType, divergence.StructureUUID.String(), err)
+	}
+}
+
+func (s service) GetReconciliation() types.ReconciliationStatus {
+	return s.reconciler.Status()
+}
+
+// recordTransition never fails the transition it records.
+func (s service) recordTransition(ctx context.Context, slot types.AcquireSlotRequest, transition types.SlotTransition) {
+	entry := types.SlotHistoryEntry{
+		VehicleUUID:   slot.VehicleUUID,
+		StructureUUID: slot.StructureUUID,
+		SlotType:      slot.SlotType,
+		SlotNumber:    slot.SlotNumber,
+		Transition:    transition,
+	}
+
+	if err := s.repository.RecordTransition(ctx, entry, config.Configuration.GetLeaderTerm()); err != nil {
+		log.Printf("[leader][history] failed to record %s of %s %d in structure %s by vehicle %s: %v", transition, slot.SlotType, slot.SlotNumber, slot.StructureUUID.String(), slot.VehicleUUID.String(), err)
+	}
+}
+
+func (s service) GetUtilization(ctx context.Context, structureUuid types.UUID, from time.Time, to time.Time) (*types.UtilizationResponse, error) {
+	visits, err := s.repository.ListSlotVisits(ctx, structureUuid, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slot visits of structure %s: %w", structureUuid.String(), err)
+	}
+
+	slots, err := s.repository.CountSlots(ctx, structureUuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count slots of structure %s: %w", structureUuid.String(), err)
+	}
+
+	return &types.UtilizationResponse{
+		StructureUUID: structureUuid,
+		From:          from,
+		To:            to,
+		Utilization:   utilization(visits, slots, from, to),
+	}, nil
+}
+
+func (s service) GetDwellTimes(ctx context.Context, structureUuid types.UUID, from time.Time, to time.Time) (*types.DurationStatsResponse, error) {
+	visits, err := s.repository.ListSlotVisits(ctx, structureUuid, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slot visits of structure %s: %w", structureUuid.String(), err)
+	}
+
+	return &types.DurationStatsResponse{
+		StructureUUID: structureUuid,
+		From:          from,
+		To:            to,
+		Stats:         durationStats(dwellTimes(visits, from, to)),
+	}, nil
+}
+
+func (s service) GetTurnaround(ctx context.Context, structureUuid types.UUID, from time.Time, to time.Time) (*types.DurationStatsResponse, error) {
+	visits, err := s.repository.ListSlotVisits(ctx, structureUuid, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slot visits of structure %s: %w", structureUuid.String(), err)
+	}
+
+	return &types.DurationStatsResponse{
+		StructureUUID: structureUuid,
+		From:          from,
+		To:            to,
+		Stats:         durationStats(turnaroundTimes(visits, from, to)),
+	}, nil
+}
+
+func (s service) EnqueueWaitlist(ctx context.Context, request types.WaitlistRequest) (*types.WaitlistResponse, error) {
+	slotType := types.GetSlotTypeByVehicleType(request.VehicleType)
+	if slotType == "" {
+		return nil, fmt.Errorf("unknown vehicle type %s: %w", request.VehicleType, utils.ErrInvalidInput)
+	}
+
+	entry := types.WaitlistEntry{
+		VehicleUUID:   request.VehicleUUID,
+		StructureUUID: request.StructureUUID,
+		StructureType: request.StructureType,
+		SlotType:      slotType,
+		Priority:      request.Priority,
+		EnqueuedAt:    time.Now(),
+	}
+
+	var position int
+	var err error
+	if consensus.Enabled() {
+		position, err = consensus.Replica.EnqueueWaitlist(entry)
+	} else {
+		position, err = s.repository.EnqueueWaitlist(ctx, entry, config.Configuration.GetLeaderTerm())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue vehicle %s: %w", request.VehicleUUID.String(), err)
+	}
+
+	response := &types.WaitlistResponse{Position: position}
+	if wait, ok := s.turnover.EstimateWait(request.StructureUUID, slotType, position); ok {
+		seconds := int(wait.Seconds())
+		response.EstimatedWaitSeconds = &seconds
+	}
+
+	return response, nil
+}
+
+func (s service) LeaveWaitlist(ctx context.Context, request types.LeaveWaitlistRequest) error {
+	if consensus.Enabled() {
+		return consensus.Replica.LeaveWaitlist(request.VehicleUUID)
+	}
+
+	if err := s.repository.LeaveWaitlist(ctx, request.VehicleUUID, config.Configuration.GetLeaderTerm()); err != nil {
+		return fmt.Errorf("failed to remove vehicle %s from waitlist: %w", request.VehicleUUID.String(), err)
+	}
+
+	return nil
+}
+
+// GrantWaitlisted grants nothing once the term the slot was released in is
+// over.
+func (s service) GrantWaitlisted(ctx context.Context, term int64, structureUuid types.UUID, slot types.StructureSlotRequest) {
+	if config.Configuration.GetLeaderTerm() != term {
+		log.Printf("[leader][waitlist] term %d ended before %s %d in structure %s was granted", term, slot.SlotType, slot.SlotNumber, structureUuid.String())
+		return
+	}
+
+	var entry *types.WaitlistEntry
+	var found bool
+	var err error
+	if consensus.Enabled() {
+		entry, found = consensus.Replica.OfferedTo(structureUuid, slot)
+	} else {
+		entry, found, err = s.offeredTo(ctx, structureUuid, slot)
+	}
+
+	if err != nil {
+		log.Printf("[leader][waitlist] failed to get vehicle offered %s %d in structure %s: %v", slot.SlotType, slot.SlotNumber, structureUuid.String(), err)
+		return
+	}
+
+	if !found {
+		return
+	}
+
+	acquireReq := types.AcquireSlotRequest{
+		VehicleUUID:          entry.VehicleUUID,
+		StructureUUID:        structureUuid,
+		StructureSlotRequest: slot,
+	}
+
+	structureResp, err := s.integration.RequestSlotToStructure(ctx, structureUuid, entry.StructureType, slot)
+	if err != nil {
+		log.Printf("[leader][waitlist] failed to request %s %d to %s %s: %v", slot.SlotType, slot.SlotNumber, entry.StructureType, structureUuid.String(), err)
+		s.withdrawOffer(ctx, term, acquireReq)
+		return
+	}
+
+	if structureResp.State != types.FreeSlotState {
+		log.Printf("[leader][waitlist] %s %d in %s %s was taken before vehicle %s got it", slot.SlotType, slot.SlotNumber, entry.StructureType, structureUuid.String(), entry.VehicleUUID.String())
+		s.withdrawOffer(ctx, term, acquireReq)
+		return
+	}
+
+	acquireResp, err := s.AcquireSlot(ctx, acquireReq)
+	if err != nil || acquireResp.Result != types.AcquiredAcquireSlotResultType {
+		log.Printf("[leader][waitlist] failed to acquire %s %d in structure %s for vehicle %s: %v", slot.SlotType, slot.SlotNumber, structureUuid.String(), entry.VehicleUUID.String(), err)
+		s.withdrawOffer(ctx, term, acquireReq)
+
+		releaseReq := types.ReleaseSlotRequest(slot)
+		if err := s.integration.ReleaseSlot(ctx, structureUuid, entry.StructureType, releaseReq); err != nil {
+			log.Printf("[leader][waitlist] failed to rollback slot request in %s %s: %v", entry.StructureType, structureUuid.String(), err)
+		}
+		return
+	}
+
+	now := time.Now()
+	grant := types.WaitlistGrantMessage{
+		VehicleUUID:   entry.VehicleUUID,
+		StructureUUID: structureUuid,
+		StructureType: entry.StructureType,
+		SlotType:      slot.SlotType,
+		SlotNumber:    slot.SlotNumber,
+		ReservedUntil: int(now.Add(config.Configuration.GetReservationTTL()).Unix()),
+		Timestamp:     int(now.Unix()),
+	}
+
+	if err := s.integration.PublishWaitlistGrant(ctx, grant); err != nil {
+		log.Printf("[leader][waitlist] failed to notify vehicle %s: %v", entry.VehicleUUID.String(), err)
+		return
+	}
+
+	log.Printf("[leader][waitlist] granted %s %d in structure %s to vehicle %s", slot.SlotType, slot.SlotNumber, structureUuid.String(), entry.VehicleUUID.String())
+}
+
+func (s service) offeredTo(ctx context.Context, structureUuid types.UUID, slot types.StructureSlotRequest) (*types.WaitlistEntry, bool, error) {
+	slotUuid, err := s.repository.GetSlotUUID(ctx, structureUuid, slot.SlotType, slot.SlotNumber)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get slot uuid: %w", err)
+	}
+
+	return s.repository.OfferedTo(ctx, slotUuid)
+}
+
+func (s service) withdrawOffer(ctx context.Context, term int64, request types.AcquireSlotRequest) {
+	var err error
+	if consensus.Enabled() {
+		err = consensus.Replica.WithdrawOffer(request)
+	} else {
+		var slotUuid types.UUID
+		slotUuid, err = s.repository.GetSlotUUID(ctx, request.StructureUUID, request.SlotType, request.SlotNumber)
+		if err == nil {
+			err = s.repository.WithdrawOffer(ctx, request.VehicleUUID, slotUuid, term)
+		}
+	}
+
+	if err != nil {
+		log.Printf("[leader][waitlist] failed to withdraw offer of %s %d in structure %s to vehicle %s: %v", request.SlotType, request.SlotNumber, request.StructureUUID.String(), request.VehicleUUID.String(), err)
+	}
+}
+
+func (s service) TransferLeadership(ctx context.Context, target types.UUID) error {
+	if target == config.Configuration.GetId() {
+		return fmt.Errorf("tower %s already is the leader: %w", target.String(), utils.ErrInvalidInput)
+	}
+
+	towers, err := s.ListHealthyTowers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list healthy towers: %w", err)
+	}
+
+	targetIndex := slices.IndexFunc(towers, func(tower types.Tower) bool { return tower.UUID == target })
+	if targetIndex < 0 {
+		return fmt.Errorf("tower %s is not healthy: %w", target.String(), utils.ErrInvalidInput)
+	}
+
+	s.slots.Close()
+
+	term, err := s.elector.Transfer(ctx, target)
+	if err != nil {
+		s.slots.Open()
+		return fmt.Errorf("failed to transfer leadership to tower %s: %w", target.String(), err)
+	}
+
+	s.lease.Revoke()
+	config.Configuration.SetLeader(target, term)
+
+	// the target is announced first so it takes over as soon as possible
+	targetTower := towers[targetIndex]
+	towers = append([]types.Tower{targetTower}, slices.Delete(towers, targetIndex, targetIndex+1)...)
+	leaderelection.AnnounceLeader(towers, target, term)
+
+	return s.elector.Resign(ctx)
+}
+
+func (s service) GetClusterView(ctx context.Context) (*types.ClusterView, error) {
+	healthyTowers, err := s.ListHealthyTowers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list healthy towers: %w", err)
+	}
+
+	towers := healthyTowers
+	if !consensus.Enabled() {
+		towers, err = s.repository.ListTowers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list towers: %w", err)
+		}
+	}
+
+	view := &types.ClusterView{
+		ClusterStatus: types.ClusterStatus{
+			TowerUUID:   config.Configuration.GetId(),
+			Role:        types.Leader.String(),
+			LeaderUUID:  config.Configuration.GetLeaderUUID(),
+			LeaderSince: config.Configuration.GetLeaderSince(),
+			Term:        config.Configuration.GetLeaderTerm(),
+			Towers:      healthyTowers,
+		},
+		ReadOnly: s.quorum.ReadOnly(),
+		Members: make([]types.ClusterMember, 0, len(towers)),
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, clusterStatusTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	members := make([]types.ClusterMember, len(towers))
+	for i, tower := range towers {
+		members[i] = types.ClusterMember{TowerUUID: tower.UUID}
+		if tower.UUID == config.Configuration.GetId() {
+			continue
+		}
+
+		wg.Go(func() {
+			status, err := s.integration.GetClusterStatus(statusCtx, tower.UUID)
+			if err != nil {
+				log.Printf("[leader][cluster] failed to get cluster status of tower %s: %v", tower.UUID.String(), err)
+				return
+			}
+
+			members[i].Reachable = true
+			members[i].Status = status
+			members[i].AgreesOnLeader = status.LeaderUUID == view.LeaderUUID && status.Term == view.Term
+		})
+	}
+	wg.Wait()
+
+	for _, member := range members {
+		if member.TowerUUID == config.Configuration.GetId() {
+			continue
+		}
+
+		view.Members = append(view.Members, member)
+		if member.Reachable && !member.AgreesOnLeader {
+			view.SplitBrain = true
+		}
+	}
+
+	return view, nil
+}
@@ -0,0 +1,276 @@
+package leader
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// towers further behind get the full state
+const maxStateChanges = 64
+
+// nil entries are removals
+type stateChange struct {
+	seq       int64
+	towers    map[types.UUID]*types.Tower
+	platforms map[types.UUID]*types.Platform
+	centrals  map[types.UUID]*types.Central
+}
+
+func (c stateChange) empty() bool {
+	return len(c.towers) == 0 && len(c.platforms) == 0 && len(c.centrals) == 0
+}
+
+type heldVersions struct {
+	towers     *types.StateVersion
+	structures *types.StateVersion
+}
+
+// clusterState versions restart with every term, in the epoch the leader
+// started in, so a leader restarted in the term it held keeps producing newer
+// versions than before its restart.
+type clusterState struct {
+	mu        sync.Mutex
+	term      int64
+	epoch     int64
+	seq       int64
+	towers    map[types.UUID]types.Tower
+	platforms map[types.UUID]types.Platform
+	centrals  map[types.UUID]types.Central
+	changes   []stateChange
+	held      map[types.UUID]heldVersions
+}
+
+func newClusterState() *clusterState {
+	return &clusterState{
+		epoch:     time.Now().UnixNano(),
+		towers:    make(map[types.UUID]types.Tower),
+		platforms: make(map[types.UUID]types.Platform),
+		centrals:  make(map[types.UUID]types.Central),
+		held:      make(map[types.UUID]heldVersions),
+	}
+}
+
+func byUUID[T any](items []T, id func(T) types.UUID) map[types.UUID]T {
+	indexed := make(map[types.UUID]T, len(items))
+	for _, item := range items {
+		indexed[id(item)] = item
+	}
+
+	return indexed
+}
+
+func diff[T comparable](current map[types.UUID]T, next map[types.UUID]T) map[types.UUID]*T {
+	changed := make(map[types.UUID]*T)
+	for id, item := range next {
+		if previous, ok := current[id]; !ok || previous != item {
+			changed[id] = &item
+		}
+	}
+
+	for id := range current {
+		if _, ok := next[id]; !ok {
+			changed[id] = nil
+		}
+	}
+
+	return changed
+}
+
+func compareUUID(a types.UUID, b types.UUID) int {
+	return strings.Compare(a.String(), b.String())
+}
+
+func sortedValues[T any](items map[types.UUID]T) []T {
+	ids := slices.SortedFunc(maps.Keys(items), compareUUID)
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, items[id])
+	}
+
+	return values
+}
+
+func merge[T any](changes []stateChange, after int64, pick func(stateChange) map[types.UUID]*T) (upserted []T, removed []types.UUID) {
+	merged := make(map[types.UUID]*T)
+	for _, change := range changes {
+		if change.seq > after {
+			maps.Copy(merged, pick(change))
+		}
+	}
+
+	upserted = []T{}
+	for _, id := range slices.SortedFunc(maps.Keys(merged), compareUUID) {
+		if merged[id] == nil {
+			removed = append(removed, id)
+		} else {
+			upserted = append(upserted, *merged[id])
+		}
+	}
+
+	return upserted, removed
+}
+
+func (s *clusterState) Update(towers []types.Tower, structures types.Structures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if term := config.Configuration.GetLeaderTerm(); term != s.term {
+		s.term = term
+		s.seq = 0
+		s.towers = make(map[types.UUID]types.Tower)
+		s.platforms = make(map[types.UUID]types.Platform)
+		s.centrals = make(map[types.UUID]types.Central)
+		s.changes = nil
+	}
+
+	nextTowers := byUUID(towers, func(tower types.Tower) types.UUID { return tower.UUID })
+	nextPlatforms := byUUID(structures.Platforms, func(platform types.Platform) types.UUID { return platform.UUID })
+	nextCentrals := byUUID(structures.Centrals, func(central types.Central) types.UUID { return central.UUID })
+
+	change := stateChange{
+		towers:    diff(s.towers, nextTowers),
+		platforms: diff(s.platforms, nextPlatforms),
+		centrals:  diff(s.centrals, nextCentrals),
+	}
+
+	if change.empty() && s.seq > 0 {
+		return
+	}
+
+	s.seq++
+	change.seq = s.seq
+	s.changes = append(s.changes, change)
+	if len(s.changes) > maxStateChanges {
+		s.changes = slices.Delete(s.changes, 0, len(s.changes)-maxStateChanges)
+	}
+
+	s.towers, s.platforms, s.centrals = nextTowers, nextPlatforms, nextCentrals
+}
+
+func (s *clusterState) version() types.StateVersion {
+	return types.StateVersion{Term: s.term, Epoch: s.epoch, Seq: s.seq}
+}
+
+func (s *clusterState) Version() *types.StateVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seq == 0 {
+		return nil
+	}
+
+	version := s.version()
+	return &version
+}
+
+func (s *clusterState) Snapshot() types.StateSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return types.StateSnapshot{
+		Version:    s.version(),
+		Towers:     sortedValues(s.towers),
+		Structures: types.Structures{Platforms: sortedValues(s.platforms), Centrals: sortedValues(s.centrals)},
+	}
+}
+
+func (s *clusterState) deltaBase(held *types.StateVersion) bool {
+	return held != nil && held.Term == s.term && held.Epoch == s.epoch && held.Seq < s.seq && len(s.changes) > 0 && held.Seq >= s.changes[0].seq-1
+}
+
+// heartbeats may have been sent before the last delivery, so older versions
+// are ignored
+func (s *clusterState) Reported(id types.UUID, towers *types.StateVersion, structures *types.StateVersion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	held := s.held[id]
+	if towers == nil || held.towers == nil || !towers.Before(*held.towers) {
+		held.towers = towers
+	}
+
+	if structures == nil || held.structures == nil || !structures.Before(*held.structures) {
+		held.structures = structures
+	}
+
+	s.held[id] = held
+}
+
+func (s *clusterState) TowersFor(id types.UUID) (*types.TowersPayload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := s.version()
+	held := s.held[id].towers
+	if s.seq == 0 || (held != nil && *held == version) {
+		return nil, false
+	}
+
+	if s.deltaBase(held) {
+		towers, removed := merge(s.changes, held.Seq, func(change stateChange) map[types.UUID]*types.Tower { return change.towers })
+		base := *held
+		return &types.TowersPayload{Towers: towers, Removed: removed, Version: &version, Base: &base}, true
+	}
+
+	return &types.TowersPayload{Towers: sortedValues(s.towers), Version: &version}, true
+}
+
+func (s *clusterState) StructuresFor(id types.UUID) (*types.StructuresPayload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := s.version()
+	held := s.held[id].structures
+	if s.seq == 0 || (held != nil && *held == version) {
+		return nil, false
+	}
+
+	if s.deltaBase(held) {
+		platforms, removedPlatforms := merge(s.changes, held.Seq, func(change stateChange) map[types.UUID]*types.Platform { return change.platforms })
+		centrals, removedCentrals := merge(s.changes, held.Seq, func(change stateChange) map[types.UUID]*types.Central { return change.centrals })
+		base := *held
+		return &types.StructuresPayload{
+			Structures:       types.Structures{Platforms: platforms, Centrals: centrals},
+			RemovedPlatforms: removedPlatforms,
+			RemovedCentrals:  removedCentrals,
+			Version:          &version,
+			Base:             &base,
+		}, true
+	}
+
+	return &types.StructuresPayload{
+		Structures: types.Structures{Platforms: sortedValues(s.platforms), Centrals: sortedValues(s.centrals)},
+		Version:    &version,
+	}, true
+}
+
+func (s *clusterState) TowersDelivered(id types.UUID, version types.StateVersion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	held := s.held[id]
+	held.towers = &version
+	s.held[id] = held
+}
+
+func (s *clusterState) StructuresDelivered(id types.UUID, version types.StateVersion) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	held := s.held[id]
+	held.structures = &version
+	s.held[id] = held
+}
+
+func (s *clusterState) Forget(id types.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.held, id)
+}
@@ -0,0 +1,283 @@
+package leader
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/google/uuid"
+)
+
+func setTerm(term int64) {
+	if config.Configuration == nil {
+		config.Configuration = &config.Config{}
+	}
+
+	config.Configuration.SetLeader(types.UUID{}, term)
+}
+
+// newTowerIDs returns n tower UUIDs in the order sortedValues lists them.
+func newTowerIDs(n int) []types.UUID {
+	ids := make([]types.UUID, 0, n)
+	for range n {
+		ids = append(ids, types.UUID(uuid.New()))
+	}
+
+	slices.SortFunc(ids, compareUUID)
+	return ids
+}
+
+func tower(id types.UUID, latitude float64) types.Tower {
+	return types.Tower{UUID: id, Latitude: latitude}
+}
+
+func versionAt(s *clusterState, seq int64) *types.StateVersion {
+	return &types.StateVersion{Term: s.term, Epoch: s.epoch, Seq: seq}
+}
+
+func TestClusterStateUpdate(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(1)
+	s := newClusterState()
+
+	if version := s.Version(); version != nil {
+		t.Fatalf("version before the first update = %s, want none", version.String())
+	}
+
+	s.Update(nil, types.Structures{})
+	if version := s.Version(); version == nil || version.Seq != 1 {
+		t.Fatalf("version after the first update = %v, want seq 1", version)
+	}
+
+	s.Update(nil, types.Structures{})
+	if version := s.Version(); version.Seq != 1 {
+		t.Fatalf("version after an update without changes = %s, want seq 1", version.String())
+	}
+
+	s.Update([]types.Tower{tower(ids[0], 1)}, types.Structures{})
+	if version := s.Version(); version.Seq != 2 {
+		t.Fatalf("version after an update with changes = %s, want seq 2", version.String())
+	}
+}
+
+func TestClusterStateResetsOnTermChange(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(1)
+	s := newClusterState()
+	s.Update([]types.Tower{tower(ids[0], 1)}, types.Structures{})
+	s.Update([]types.Tower{tower(ids[0], 2)}, types.Structures{})
+	s.TowersDelivered(ids[0], *s.Version())
+
+	setTerm(2)
+	s.Update([]types.Tower{tower(ids[0], 2)}, types.Structures{})
+
+	version := s.Version()
+	if version.Term != 2 || version.Seq != 1 || len(s.changes) != 1 {
+		t.Fatalf("version after a term change = %s with %d changes, want 2.%d.1 with 1 change", version.String(), len(s.changes), s.epoch)
+	}
+
+	payload, ok := s.TowersFor(ids[0])
+	if !ok || payload.Base != nil || !reflect.DeepEqual(payload.Towers, []types.Tower{tower(ids[0], 2)}) {
+		t.Fatalf("tower holding a version of the previous term got %+v, want the full state", payload)
+	}
+}
+
+func TestClusterStateTrimsChanges(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(1)
+	s := newClusterState()
+	for seq := range maxStateChanges + 6 {
+		s.Update([]types.Tower{tower(ids[0], float64(seq))}, types.Structures{})
+	}
+
+	if len(s.changes) != maxStateChanges || s.changes[0].seq != 7 {
+		t.Fatalf("kept %d changes from seq %d, want %d from seq 7", len(s.changes), s.changes[0].seq, maxStateChanges)
+	}
+}
+
+func TestClusterStateTowersFor(t *testing.T) {
+	ids := newTowerIDs(3)
+	all := []types.Tower{tower(ids[0], 1), tower(ids[1], 1), tower(ids[2], 1)}
+
+	tests := []struct {
+		name    string
+		updates [][]types.Tower
+		held    func(s *clusterState) *types.StateVersion
+		send    bool
+		delta   bool
+		towers  []types.Tower
+		removed []types.UUID
+	}{
+		{
+			name:    "tower holding nothing gets the full state",
+			updates: [][]types.Tower{all},
+			held:    func(s *clusterState) *types.StateVersion { return nil },
+			send:    true,
+			towers:  all,
+		},
+		{
+			name:    "tower holding the current version gets nothing",
+			updates: [][]types.Tower{all},
+			held:    func(s *clusterState) *types.StateVersion { return versionAt(s, 1) },
+		},
+		{
+			name:    "tower behind gets the changed towers",
+			updates: [][]types.Tower{all, {tower(ids[0], 1), tower(ids[1], 2), tower(ids[2], 1)}, {tower(ids[0], 1), tower(ids[1], 2), tower(ids[2], 3)}},
+			held:    func(s *clusterState) *types.StateVersion { return versionAt(s, 1) },
+			send:    true,
+			delta:   true,
+			towers:  []types.Tower{tower(ids[1], 2), tower(ids[2], 3)},
+		},
+		{
+			name:    "removed towers are sent as removals",
+			updates: [][]types.Tower{all, {tower(ids[0], 1), tower(ids[2], 2)}},
+			held:    func(s *clusterState) *types.StateVersion { return versionAt(s, 1) },
+			send:    true,
+			delta:   true,
+			towers:  []types.Tower{tower(ids[2], 2)},
+			removed: []types.UUID{ids[1]},
+		},
+		{
+			name:    "tower removed and added back is sent as changed",
+			updates: [][]types.Tower{all, {tower(ids[0], 1), tower(ids[2], 1)}, all},
+			held:    func(s *clusterState) *types.StateVersion { return versionAt(s, 1) },
+			send:    true,
+			delta:   true,
+			towers:  []types.Tower{tower(ids[1], 1)},
+		},
+		{
+			name:    "tower holding a version of another epoch gets the full state",
+			updates: [][]types.Tower{all, {tower(ids[0], 2), tower(ids[1], 1), tower(ids[2], 1)}},
+			held: func(s *clusterState) *types.StateVersion {
+				held := versionAt(s, 1)
+				held.Epoch--
+				return held
+			},
+			send:   true,
+			towers: []types.Tower{tower(ids[0], 2), tower(ids[1], 1), tower(ids[2], 1)},
+		},
+		{
+			name:    "tower holding a version newer than the current one gets the full state",
+			updates: [][]types.Tower{all},
+			held:    func(s *clusterState) *types.StateVersion { return versionAt(s, 2) },
+			send:    true,
+			towers:  all,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTerm(1)
+			s := newClusterState()
+			for _, towers := range tt.updates {
+				s.Update(towers, types.Structures{})
+			}
+
+			if held := tt.held(s); held != nil {
+				s.TowersDelivered(ids[0], *held)
+			}
+
+			payload, ok := s.TowersFor(ids[0])
+			if ok != tt.send {
+				t.Fatalf("TowersFor() sends %t, want %t", ok, tt.send)
+			}
+
+			if !ok {
+				return
+			}
+
+			if *payload.Version != *s.Version() {
+				t.Errorf("payload version = %s, want %s", payload.Version.String(), s.Version().String())
+			}
+
+			if delta := payload.Base != nil; delta != tt.delta {
+				t.Errorf("payload is a delta: %t, want %t", delta, tt.delta)
+			}
+
+			if !reflect.DeepEqual(payload.Towers, tt.towers) || !reflect.DeepEqual(payload.Removed, tt.removed) {
+				t.Errorf("payload holds towers %+v and removals %v, want %+v and %v", payload.Towers, payload.Removed, tt.towers, tt.removed)
+			}
+		})
+	}
+}
+
+func TestClusterStateTowersForHeldVersionTooOld(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(1)
+	s := newClusterState()
+	for seq := range maxStateChanges + 2 {
+		s.Update([]types.Tower{tower(ids[0], float64(seq))}, types.Structures{})
+	}
+
+	// the oldest change kept builds on the version right before it
+	s.TowersDelivered(ids[0], *versionAt(s, s.changes[0].seq-1))
+	if payload, ok := s.TowersFor(ids[0]); !ok || payload.Base == nil {
+		t.Fatalf("tower holding the base of the oldest change kept got %+v, want a delta", payload)
+	}
+
+	s.TowersDelivered(ids[0], *versionAt(s, s.changes[0].seq-2))
+	if payload, ok := s.TowersFor(ids[0]); !ok || payload.Base != nil {
+		t.Fatalf("tower holding a version older than the changes kept got %+v, want the full state", payload)
+	}
+}
+
+func TestClusterStateStructuresFor(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(4)
+	platform := func(id types.UUID, latitude float64) types.Platform {
+		return types.Platform{UUID: id, Structure: types.Structure{Latitude: latitude}}
+	}
+	central := func(id types.UUID, latitude float64) types.Central {
+		return types.Central{UUID: id, Structure: types.Structure{Latitude: latitude}}
+	}
+
+	s := newClusterState()
+	s.Update(nil, types.Structures{
+		Platforms: []types.Platform{platform(ids[0], 1), platform(ids[1], 1)},
+		Centrals:  []types.Central{central(ids[2], 1), central(ids[3], 1)},
+	})
+	s.StructuresDelivered(ids[0], *s.Version())
+	s.Update(nil, types.Structures{
+		Platforms: []types.Platform{platform(ids[0], 2)},
+		Centrals:  []types.Central{central(ids[3], 1)},
+	})
+
+	payload, ok := s.StructuresFor(ids[0])
+	if !ok || payload.Base == nil {
+		t.Fatalf("tower one version behind got %+v, want a delta", payload)
+	}
+
+	if !reflect.DeepEqual(payload.Structures.Platforms, []types.Platform{platform(ids[0], 2)}) || len(payload.Structures.Centrals) != 0 {
+		t.Errorf("delta holds %+v, want the changed platform only", payload.Structures)
+	}
+
+	if !reflect.DeepEqual(payload.RemovedPlatforms, []types.UUID{ids[1]}) || !reflect.DeepEqual(payload.RemovedCentrals, []types.UUID{ids[2]}) {
+		t.Errorf("delta removes platforms %v and centrals %v, want %v and %v", payload.RemovedPlatforms, payload.RemovedCentrals, ids[1:2], ids[2:3])
+	}
+
+	s.Forget(ids[0])
+	if payload, ok := s.StructuresFor(ids[0]); !ok || payload.Base != nil || len(payload.Structures.Platforms) != 1 || len(payload.Structures.Centrals) != 1 {
+		t.Fatalf("forgotten tower got %+v, want the full state", payload)
+	}
+}
+
+func TestClusterStateReportedKeepsNewerDeliveries(t *testing.T) {
+	setTerm(1)
+	ids := newTowerIDs(1)
+	s := newClusterState()
+	s.Update([]types.Tower{tower(ids[0], 1)}, types.Structures{})
+	s.Update([]types.Tower{tower(ids[0], 2)}, types.Structures{})
+
+	s.TowersDelivered(ids[0], *versionAt(s, 2))
+	s.Reported(ids[0], versionAt(s, 1), nil)
+	if _, ok := s.TowersFor(ids[0]); ok {
+		t.Fatalf("heartbeat sent before the delivery made the tower fall behind")
+	}
+
+	s.Reported(ids[0], nil, nil)
+	if payload, ok := s.TowersFor(ids[0]); !ok || payload.Base != nil {
+		t.Fatalf("tower reporting no version got %+v, want the full state", payload)
+	}
+}
@@ -0,0 +1,65 @@
+package leader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+// weight of the latest interval between two released slots in the average
+const turnoverWeight = 0.3
+
+type turnoverKey struct {
+	structureUuid types.UUID
+	slotType      types.SlotType
+}
+
+type turnoverStats struct {
+	releasedAt      time.Time
+	averageInterval time.Duration
+}
+
+type turnover struct {
+	mu    sync.Mutex
+	stats map[turnoverKey]turnoverStats
+}
+
+func newTurnover() *turnover {
+	return &turnover{
+		stats: make(map[turnoverKey]turnoverStats),
+	}
+}
+
+func (t *turnover) Released(structureUuid types.UUID, slotType types.SlotType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := turnoverKey{structureUuid: structureUuid, slotType: slotType}
+	stats, ok := t.stats[key]
+	now := time.Now()
+	if ok {
+		interval := now.Sub(stats.releasedAt)
+		if stats.averageInterval == 0 {
+			stats.averageInterval = interval
+		} else {
+			stats.averageInterval = time.Duration(turnoverWeight*float64(interval) + (1-turnoverWeight)*float64(stats.averageInterval))
+		}
+	}
+
+	stats.releasedAt = now
+	t.stats[key] = stats
+}
+
+// EstimateWait reports false until two slots were released.
+func (t *turnover) EstimateWait(structureUuid types.UUID, slotType types.SlotType, position int) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.stats[turnoverKey{structureUuid: structureUuid, slotType: slotType}]
+	if !ok || stats.averageInterval == 0 {
+		return 0, false
+	}
+
+	return time.Duration(position) * stats.averageInterval, true
+}
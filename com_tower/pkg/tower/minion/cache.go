@@ -0,0 +1,71 @@
+package minion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+const cacheSnapshotFile = "cache.json"
+
+// cacheFile lets a tower restarted while the leader is unreachable still serve
+// the state last synced.
+type cacheFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newCacheFile(dir string) (*cacheFile, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache snapshot dir: %w", err)
+	}
+
+	return &cacheFile{path: filepath.Join(dir, cacheSnapshotFile)}, nil
+}
+
+func (c *cacheFile) Load() (*types.CacheSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+
+	var snapshot types.CacheSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode cache snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+func (c *cacheFile) Save(snapshot types.CacheSnapshot) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to replace cache snapshot: %w", err)
+	}
+
+	return nil
+}
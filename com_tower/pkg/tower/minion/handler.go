@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
-	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -34,6 +33,10 @@ func (h handler) ListStructures(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, structures)
 }
 
+func (h handler) GetClusterStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.GetClusterStatus())
+}
+
 func (h handler) SyncTowers(ctx *gin.Context) {
 	var towers types.TowersPayload
 	if err := ctx.ShouldBindJSON(&towers); err != nil {
@@ -42,19 +45,29 @@ func (h handler) SyncTowers(ctx *gin.Context) {
 		return
 	}
 
-	h.service.SyncTowers(towers)
+	if err := h.service.SyncTowers(towers); err != nil {
+		log.Printf("failed to sync towers: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
 func (h handler) SyncStructures(ctx *gin.Context) {
-	var structures types.Structures
+	var structures types.StructuresPayload
 	if err := ctx.ShouldBindJSON(&structures); err != nil {
 		log.Printf("failed to unmarshal request: %v", err)
 		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
 		return
 	}
 
-	h.service.SyncStructures(structures)
+	if err := h.service.SyncStructures(structures); err != nil {
+		log.Printf("failed to sync structures: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusNoContent, nil)
 }
 
@@ -66,7 +79,7 @@ func (h handler) CheckSlotAvailability(ctx *gin.Context) {
 		return
 	}
 
-	response, err := h.service.CheckSlotAvailability(ctx, slotRequest)
+	response, err := h.service.CheckSlotAvailability(ctx, slotRequest, ctx.GetHeader(utils.IdempotencyKeyHeader))
 	if err != nil {
 		log.Printf("failed to check slot availability: %v", err)
 		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
@@ -76,6 +89,116 @@ func (h handler) CheckSlotAvailability(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+func (h handler) AssignSlot(ctx *gin.Context) {
+	var assignRequest types.AssignSlotRequest
+	if err := ctx.ShouldBindJSON(&assignRequest); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	response, err := h.service.AssignSlot(ctx, assignRequest)
+	if err != nil {
+		log.Printf("failed to assign slot: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) EnqueueWaitlist(ctx *gin.Context) {
+	var waitlistRequest types.WaitlistRequest
+	if err := ctx.ShouldBindJSON(&waitlistRequest); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	response, err := h.service.EnqueueWaitlist(ctx, waitlistRequest)
+	if err != nil {
+		log.Printf("failed to enqueue vehicle: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) LeaveWaitlist(ctx *gin.Context) {
+	var leaveRequest types.LeaveWaitlistRequest
+	if err := ctx.ShouldBindJSON(&leaveRequest); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	if err := h.service.LeaveWaitlist(ctx, leaveRequest); err != nil {
+		log.Printf("failed to remove vehicle from waitlist: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h handler) CreateBooking(ctx *gin.Context) {
+	var bookingRequest types.BookingRequest
+	if err := ctx.ShouldBindJSON(&bookingRequest); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	response, err := h.service.CreateBooking(ctx, bookingRequest)
+	if err != nil {
+		log.Printf("failed to create booking: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) CancelBooking(ctx *gin.Context) {
+	var cancelRequest types.CancelBookingRequest
+	if err := ctx.ShouldBindJSON(&cancelRequest); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	if err := h.service.CancelBooking(ctx, cancelRequest); err != nil {
+		log.Printf("failed to cancel booking: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (h handler) ListBookings(ctx *gin.Context) {
+	var query types.BookingsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil || query.StructureUUID == (types.UUID{}) {
+		log.Printf("failed to parse bookings query: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrInvalidInput)
+		return
+	}
+
+	response, err := h.service.ListBookings(ctx, query)
+	if err != nil {
+		log.Printf("failed to list bookings: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (h handler) ListSagas(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.ListSagas())
+}
+
 func (h handler) HandleElection(ctx *gin.Context) {
 	var req types.ElectionRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -84,29 +207,42 @@ func (h handler) HandleElection(ctx *gin.Context) {
 		return
 	}
 
-	uptime := config.Configuration.GetUptimeSeconds()
+	candidate := config.Configuration.GetCandidate()
 
 	var response types.ElectionResponse
-	if uptime > req.CandidateUptime {
-		log.Printf("[minion][election] my uptime (%.2fs) > candidate's uptime (%.2fs): starting my own election", uptime, req.CandidateUptime)
-		if !config.Configuration.IsLeader() {
-			go leaderelection.StartElection(h.service.ListTowers())
-		}
+	// the candidate stands down, and this tower campaigns once its own
+	// healthchecks fail, through the jittered and pre-voted path
+	if candidate.Outranks(req.Candidate) {
+		log.Printf("[minion][election] I outrank candidate %s (priority %d, started at %d)", req.Candidate.UUID.String(), req.Candidate.Priority, req.Candidate.StartedAt)
 		response = types.ElectionResponse{
-			Uptime:          uptime,
-			HasHigherUptime: true,
+			Tower:    candidate,
+			Outranks: true,
 		}
 	} else {
-		log.Printf("my uptime (%.2fs) <= candidate's uptime (%.2fs): confirming vote in candidate", uptime, req.CandidateUptime)
+		log.Printf("candidate %s (priority %d, started at %d) outranks me: confirming vote in candidate", req.Candidate.UUID.String(), req.Candidate.Priority, req.Candidate.StartedAt)
 		response = types.ElectionResponse{
-			Uptime:          uptime,
-			HasHigherUptime: false,
+			Tower:    candidate,
+			Outranks: false,
 		}
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+func (h handler) HandlePreVote(ctx *gin.Context) {
+	var req types.PreVoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Printf("failed to unmarshal request: %v", err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	reachable := h.service.IsLeaderReachable()
+	log.Printf("[minion][election][pre_vote] candidate asked about leader %s in term %d: reachable: %t", req.LeaderUUID.String(), req.Term, reachable)
+
+	ctx.JSON(http.StatusOK, types.PreVoteResponse{LeaderReachable: reachable})
+}
+
 func (h handler) SetNewLeader(ctx *gin.Context) {
 	var req types.NewLeaderRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -115,7 +251,16 @@ func (h handler) SetNewLeader(ctx *gin.Context) {
 		return
 	}
 
-	config.Configuration.SetLeaderUUID(req.NewLeaderUUID)
+	if err := h.service.AcceptNewLeader(ctx, req); err != nil {
+		log.Printf("[minion][audit][election] rejected leader %s announced with term %d by %s: %v", req.NewLeaderUUID.String(), req.Term, ctx.ClientIP(), err)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, err)
+		return
+	}
+
+	if config.Configuration.IsLeader() {
+		log.Printf("[minion][election] leadership handed over to me for term %d", req.Term)
+		go h.service.TakeOverLeadership()
+	}
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
@@ -0,0 +1,57 @@
+package minion
+
+import (
+	"sync"
+	"time"
+)
+
+type heartbeat struct {
+	mu           sync.RWMutex
+	lastSuccess  time.Time
+	lastFailure  time.Time
+	failureCount int
+}
+
+func newHeartbeat() *heartbeat {
+	return &heartbeat{}
+}
+
+func (h *heartbeat) Succeeded() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastSuccess = time.Now()
+	h.failureCount = 0
+}
+
+func (h *heartbeat) Failed() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastFailure = time.Now()
+	h.failureCount++
+	return h.failureCount
+}
+
+func (h *heartbeat) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.failureCount = 0
+}
+
+// Reachable ignores Reset, so a tower campaigning after its healthchecks
+// failed still reports the leader unreachable.
+func (h *heartbeat) Reachable(timeout time.Duration) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lastSuccess.After(h.lastFailure) && time.Since(h.lastSuccess) < timeout
+}
+
+func (h *heartbeat) Status() (time.Time, int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lastSuccess, h.failureCount
+}
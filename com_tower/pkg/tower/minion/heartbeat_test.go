@@ -0,0 +1,71 @@
+package minion
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeatReachable(t *testing.T) {
+	const timeout = time.Minute
+
+	tests := []struct {
+		name      string
+		record    func(h *heartbeat)
+		reachable bool
+	}{
+		{
+			name:      "no healthcheck yet",
+			record:    func(h *heartbeat) {},
+			reachable: false,
+		},
+		{
+			name:      "last healthcheck succeeded",
+			record:    func(h *heartbeat) { h.Succeeded() },
+			reachable: true,
+		},
+		{
+			name: "last healthcheck failed within the timeout",
+			record: func(h *heartbeat) {
+				h.Succeeded()
+				h.Failed()
+			},
+			reachable: false,
+		},
+		{
+			name: "failures reset after campaigning",
+			record: func(h *heartbeat) {
+				h.Succeeded()
+				h.Failed()
+				h.Reset()
+			},
+			reachable: false,
+		},
+		{
+			name: "leader reached again after failing",
+			record: func(h *heartbeat) {
+				h.Failed()
+				h.Succeeded()
+			},
+			reachable: true,
+		},
+		{
+			name: "last success older than the timeout",
+			record: func(h *heartbeat) {
+				h.Succeeded()
+				h.lastSuccess = h.lastSuccess.Add(-2 * timeout)
+			},
+			reachable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHeartbeat()
+			tt.record(h)
+
+			if reachable := h.Reachable(timeout); reachable != tt.reachable {
+				t.Fatalf("Reachable() = %t, want %t", reachable, tt.reachable)
+			}
+		})
+	}
+}
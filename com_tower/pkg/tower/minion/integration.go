@@ -8,9 +8,12 @@ import (
 	"io"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
@@ -61,7 +64,45 @@ func (i integration) RequestSlotToStructure(ctx context.Context, slotRequest typ
 	}
 }
 
-func (i integration) AcquireSlotLockInTowerLeader(ctx context.Context, slotRequest types.AcquireSlotRequest) (*types.AcquireSlotResponse, error) {
+func (i integration) ListFreeSlotsInTowerLeader(ctx context.Context, slotsRequest types.FreeSlotsRequest) (*types.FreeSlotsResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/free-slots", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(slotsRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal free slots request for %s in structure %s: %w", slotsRequest.SlotType, slotsRequest.StructureUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create free slots request for %s in structure %s: %w", slotsRequest.SlotType, slotsRequest.StructureUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request free slots for %s in structure %s: %w", slotsRequest.SlotType, slotsRequest.StructureUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var slotsResp types.FreeSlotsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&slotsResp); err != nil {
+			return nil, fmt.Errorf("failed to decode free slots response body for %s in structure %s: %w", slotsRequest.SlotType, slotsRequest.StructureUUID.String(), err)
+		}
+
+		return &slotsResp, nil
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to request free slots for %s in structure %s: %w", slotsRequest.SlotType, slotsRequest.StructureUUID.String(), utils.ErrStaleTerm)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) AcquireSlotLockInTowerLeader(ctx context.Context, slotRequest types.AcquireSlotRequest, idempotencyKey string) (*types.AcquireSlotResponse, error) {
 	url := fmt.Sprintf("http://t-%s.tower.%s/acquire-slot", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
 	payload, err := json.Marshal(slotRequest)
 	if err != nil {
@@ -73,6 +114,9 @@ func (i integration) AcquireSlotLockInTowerLeader(ctx context.Context, slotReque
 		return nil, fmt.Errorf("failed to create slot acquire request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
 	}
 
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+	idempotency.SetKeyHeader(req, idempotencyKey)
+
 	resp, err := i.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request a slot acquire for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
@@ -89,26 +133,113 @@ func (i integration) AcquireSlotLockInTowerLeader(ctx context.Context, slotReque
 
 		return &acquireResp, nil
 
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to request a slot acquire for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), utils.ErrStaleTerm)
+
+	case http.StatusServiceUnavailable:
+		return nil, fmt.Errorf("failed to request a slot acquire for %s %d in structure %s: %w: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), utils.ErrLeaderReadOnly, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body))
+
 	default:
 		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
 	}
 }
 
-func (i integration) SendHealthCheck(ctx context.Context) error {
-	url := fmt.Sprintf("http://t-%s.tower.%s/tower-health", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
-	payload, err := json.Marshal(types.TowerHealthRequest{Id: config.Configuration.GetId()})
+func (i integration) PreemptSlotInTowerLeader(ctx context.Context, slotRequest types.AcquireSlotRequest) (*types.AcquireSlotResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/preempt-slot", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(slotRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal slot preemption request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create slot preemption request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request a slot preemption for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var preemptResp types.AcquireSlotResponse
+		if err := json.NewDecoder(resp.Body).Decode(&preemptResp); err != nil {
+			return nil, fmt.Errorf("failed to decode slot preemption response body for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+		}
+
+		return &preemptResp, nil
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to request a slot preemption for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), utils.ErrStaleTerm)
+
+	case http.StatusServiceUnavailable:
+		return nil, fmt.Errorf("failed to request a slot preemption for %s %d in structure %s: %w: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), utils.ErrLeaderReadOnly, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body))
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) EnqueueWaitlistInTowerLeader(ctx context.Context, waitlistRequest types.WaitlistRequest) (*types.WaitlistResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/waitlist", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(waitlistRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal waitlist request for vehicle %s: %w", waitlistRequest.VehicleUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create waitlist request for vehicle %s: %w", waitlistRequest.VehicleUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute waitlist request for vehicle %s: %w", waitlistRequest.VehicleUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var waitlistResp types.WaitlistResponse
+		if err := json.NewDecoder(resp.Body).Decode(&waitlistResp); err != nil {
+			return nil, fmt.Errorf("failed to decode waitlist response body for vehicle %s: %w", waitlistRequest.VehicleUUID.String(), err)
+		}
+
+		return &waitlistResp, nil
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to enqueue vehicle %s: %w", waitlistRequest.VehicleUUID.String(), utils.ErrStaleTerm)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) LeaveWaitlistInTowerLeader(ctx context.Context, leaveRequest types.LeaveWaitlistRequest) error {
+	url := fmt.Sprintf("http://t-%s.tower.%s/waitlist/leave", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(leaveRequest)
 	if err != nil {
-		return fmt.Errorf("failed to marshal healthcheck request for tower %s: %w", config.Configuration.GetIdAsString(), err)
+		return fmt.Errorf("failed to marshal leave waitlist request for vehicle %s: %w", leaveRequest.VehicleUUID.String(), err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
 	if err != nil {
-		return fmt.Errorf("failed to create healthcheck request for tower %s: %w", config.Configuration.GetIdAsString(), err)
+		return fmt.Errorf("failed to create leave waitlist request for vehicle %s: %w", leaveRequest.VehicleUUID.String(), err)
 	}
 
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
 	resp, err := i.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("tower %s: %w: %w", config.Configuration.GetIdAsString(), utils.ErrLeaderUnreachable, err)
+		return fmt.Errorf("failed to execute leave waitlist request for vehicle %s: %w", leaveRequest.VehicleUUID.String(), err)
 	}
 
 	defer resp.Body.Close()
@@ -121,14 +252,229 @@ func (i integration) SendHealthCheck(ctx context.Context) error {
 
 		return nil
 
-	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-		return fmt.Errorf("tower %s: %w: http error code %d", config.Configuration.GetIdAsString(), utils.ErrLeaderUnreachable, resp.StatusCode) 
+	case http.StatusConflict:
+		return fmt.Errorf("failed to remove vehicle %s from waitlist: %w", leaveRequest.VehicleUUID.String(), utils.ErrStaleTerm)
+
+	default:
+		return utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) CreateBookingInTowerLeader(ctx context.Context, bookingRequest types.BookingRequest) (*types.BookingResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/bookings", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(bookingRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal booking request for vehicle %s: %w", bookingRequest.VehicleUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking request for vehicle %s: %w", bookingRequest.VehicleUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute booking request for vehicle %s: %w", bookingRequest.VehicleUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var bookingResp types.BookingResponse
+		if err := json.NewDecoder(resp.Body).Decode(&bookingResp); err != nil {
+			return nil, fmt.Errorf("failed to decode booking response body for vehicle %s: %w", bookingRequest.VehicleUUID.String(), err)
+		}
+
+		return &bookingResp, nil
+
+	case http.StatusBadRequest:
+		return nil, fmt.Errorf("failed to book slot for vehicle %s: %w: %w", bookingRequest.VehicleUUID.String(), utils.ErrInvalidInput, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body))
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to book slot for vehicle %s: %w", bookingRequest.VehicleUUID.String(), utils.ErrStaleTerm)
+
+	case http.StatusNotImplemented:
+		return nil, fmt.Errorf("failed to book slot for vehicle %s: %w", bookingRequest.VehicleUUID.String(), utils.ErrRaftUnsupported)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) CancelBookingInTowerLeader(ctx context.Context, cancelRequest types.CancelBookingRequest) error {
+	url := fmt.Sprintf("http://t-%s.tower.%s/bookings/cancel", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(cancelRequest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cancel booking request for booking %s: %w", cancelRequest.BookingUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create cancel booking request for booking %s: %w", cancelRequest.BookingUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute cancel booking request for booking %s: %w", cancelRequest.BookingUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		if _, err = io.Copy(io.Discard, resp.Body); err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		return nil
+
+	case http.StatusConflict:
+		return fmt.Errorf("failed to cancel booking %s: %w", cancelRequest.BookingUUID.String(), utils.ErrStaleTerm)
+
+	case http.StatusNotImplemented:
+		return fmt.Errorf("failed to cancel booking %s: %w", cancelRequest.BookingUUID.String(), utils.ErrRaftUnsupported)
 
 	default:
 		return utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
 	}
 }
 
+func (i integration) ListBookingsInTowerLeader(ctx context.Context, query types.BookingsQuery) (*types.BookingsResponse, error) {
+	params := url.Values{}
+	params.Set("structure_uuid", query.StructureUUID.String())
+	if query.From != nil {
+		params.Set("from", query.From.Format(time.RFC3339))
+	}
+
+	if query.To != nil {
+		params.Set("to", query.To.Format(time.RFC3339))
+	}
+
+	url := fmt.Sprintf("http://t-%s.tower.%s/bookings?%s", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns(), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list bookings request for structure %s: %w", query.StructureUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute list bookings request for structure %s: %w", query.StructureUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var bookingsResp types.BookingsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&bookingsResp); err != nil {
+			return nil, fmt.Errorf("failed to decode list bookings response body for structure %s: %w", query.StructureUUID.String(), err)
+		}
+
+		return &bookingsResp, nil
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to list bookings of structure %s: %w", query.StructureUUID.String(), utils.ErrStaleTerm)
+
+	case http.StatusNotImplemented:
+		return nil, fmt.Errorf("failed to list bookings of structure %s: %w", query.StructureUUID.String(), utils.ErrRaftUnsupported)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) SendHealthCheck(ctx context.Context, request types.TowerHealthRequest) (*types.TowerHealthResponse, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/tower-health", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal healthcheck request for tower %s: %w", config.Configuration.GetIdAsString(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create healthcheck request for tower %s: %w", config.Configuration.GetIdAsString(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tower %s: %w: %w", config.Configuration.GetIdAsString(), utils.ErrLeaderUnreachable, err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var healthResp types.TowerHealthResponse
+		if err := json.NewDecoder(resp.Body).Decode(&healthResp); err != nil {
+			return nil, fmt.Errorf("failed to decode healthcheck response body: %w", err)
+		}
+
+		return &healthResp, nil
+
+	// leaders that do not report their state version
+	case http.StatusNoContent:
+		if _, err = io.Copy(io.Discard, resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		return &types.TowerHealthResponse{}, nil
+
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, fmt.Errorf("tower %s: %w: http error code %d", config.Configuration.GetIdAsString(), utils.ErrLeaderUnreachable, resp.StatusCode) 
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("tower %s: %w", config.Configuration.GetIdAsString(), utils.ErrStaleTerm)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) GetStateSnapshotFromTowerLeader(ctx context.Context) (*types.StateSnapshot, error) {
+	url := fmt.Sprintf("http://t-%s.tower.%s/state", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state snapshot request: %w", err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request state snapshot: %w: %w", utils.ErrLeaderUnreachable, err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var snapshot types.StateSnapshot
+		if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode state snapshot response body: %w", err)
+		}
+
+		return &snapshot, nil
+
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, fmt.Errorf("failed to request state snapshot: %w: http error code %d", utils.ErrLeaderUnreachable, resp.StatusCode)
+
+	case http.StatusConflict:
+		return nil, fmt.Errorf("failed to request state snapshot: %w", utils.ErrStaleTerm)
+
+	default:
+		return nil, utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
 func (i integration) ReleaseSlot(ctx context.Context, structureUuid types.UUID, structureType types.StructureType, slotRequest types.ReleaseSlotRequest) error {
 	url := fmt.Sprintf("http://s-%s.%s.%s/release-slot", structureUuid.String(), structureType, config.Configuration.GetBaseDns())
 	payload, err := json.Marshal(slotRequest)
@@ -161,7 +507,45 @@ func (i integration) ReleaseSlot(ctx context.Context, structureUuid types.UUID,
 	}
 }
 
-func (i integration) ReleaseSlotLock(ctx context.Context, slotRequest types.ReleaseSlotLockRequest) error {
+func (i integration) OccupySlotInTowerLeader(ctx context.Context, slotRequest types.OccupySlotRequest, idempotencyKey string) error {
+	url := fmt.Sprintf("http://t-%s.tower.%s/occupy-slot", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
+	payload, err := json.Marshal(slotRequest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal occupy slot request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create occupy slot request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+	idempotency.SetKeyHeader(req, idempotencyKey)
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute occupy slot request for %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), err)
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		if _, err = io.Copy(io.Discard, resp.Body); err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		return nil
+
+	case http.StatusConflict:
+		return fmt.Errorf("failed to occupy %s %d in structure %s: %w", slotRequest.SlotType, slotRequest.SlotNumber, slotRequest.StructureUUID.String(), utils.ErrStaleTerm)
+
+	default:
+		return utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
+	}
+}
+
+func (i integration) ReleaseSlotLock(ctx context.Context, slotRequest types.ReleaseSlotLockRequest, idempotencyKey string) error {
 	url := fmt.Sprintf("http://t-%s.tower.%s/release-slot", config.Configuration.GetLeaderUUIDAsString(), config.Configuration.GetBaseDns())
 	payload, err := json.Marshal(slotRequest)
 	if err != nil {
@@ -173,6 +557,9 @@ func (i integration) ReleaseSlotLock(ctx context.Context, slotRequest types.Rele
 		return fmt.Errorf("failed to create release slot request for tower %s: %w", config.Configuration.GetIdAsString(), err)
 	}
 
+	utils.SetLeaderTermHeader(req, config.Configuration.GetLeaderTerm())
+	idempotency.SetKeyHeader(req, idempotencyKey)
+
 	resp, err := i.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute release slot request for tower %s: %w", config.Configuration.GetIdAsString(), err)
@@ -188,6 +575,9 @@ func (i integration) ReleaseSlotLock(ctx context.Context, slotRequest types.Rele
 
 		return nil
 
+	case http.StatusConflict:
+		return fmt.Errorf("failed to release slot lock for tower %s: %w", config.Configuration.GetIdAsString(), utils.ErrStaleTerm)
+
 	default:
 		return utils.HttpErrorNotHandled(resp.StatusCode, resp.Body)
 	}
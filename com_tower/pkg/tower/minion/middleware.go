@@ -9,14 +9,22 @@ import (
 	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 	"github.com/gin-gonic/gin"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var auditedPaths = map[string]bool{
+	"/slots":        true,
+	"/slots/assign": true,
+}
+
 func AuditRequests() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if ctx.Request.URL.Path != "/slots" || ctx.Request.Method != http.MethodPost {
+		if !auditedPaths[ctx.Request.URL.Path] || ctx.Request.Method != http.MethodPost {
 			ctx.Next()
 			return
 		}
@@ -35,6 +43,11 @@ func AuditRequests() gin.HandlerFunc {
 
 		ctx.Next()
 
+		// replays were audited when first served
+		if ctx.GetBool(idempotency.ReplayedKey) {
+			return
+		}
+
 		var slotReq types.SlotRequest
 		defer ctx.Request.Body.Close()
 		if err := json.NewDecoder(bodyCopy).Decode(&slotReq); err != nil {
@@ -51,6 +64,11 @@ func AuditRequests() gin.HandlerFunc {
 			}
 
 			result = types.GetResultTypeBySlotState(slotResp.State)
+
+			// assigned slots are picked by the tower
+			if slotResp.SlotNumber != 0 {
+				slotReq.SlotNumber = slotResp.SlotNumber
+			}
 		}
 
 		auditReq := types.AuditRequest{
@@ -81,3 +99,51 @@ func AuditRequests() gin.HandlerFunc {
 		}
 	}
 }
+
+// RejectStaleTerm adopts newer terms by refreshing the leader from the
+// election backend, so the term never pairs with the leader of another term.
+func RejectStaleTerm(svc service) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		term := utils.GetLeaderTermFromHeader(ctx.Request.Header)
+		if term < config.Configuration.GetLeaderTerm() {
+			log.Printf("[minion][term][middleware] rejecting %s push with stale term %d: current term is %d", ctx.Request.URL.Path, term, config.Configuration.GetLeaderTerm())
+			utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrStaleTerm)
+			ctx.Abort()
+			return
+		}
+
+		if term > config.Configuration.GetLeaderTerm() && !svc.RefreshLeader(ctx) {
+			log.Printf("[minion][term][middleware] %s push carries term %d, newer than the one known by the election backend", ctx.Request.URL.Path, term)
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireSyncedState serves state restored from the cache snapshot flagged as
+// stale, so a restarted tower never serves an empty cache.
+func RequireSyncedState(svc service) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if consensus.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		state := svc.repository.Snapshot()
+		if state.IsSynced() {
+			ctx.Next()
+			return
+		}
+
+		if stale, savedAt := state.Staleness(); stale {
+			ctx.Header(utils.StateStaleHeader, "true")
+			ctx.Header(utils.StateSavedAtHeader, savedAt.Format(time.RFC3339))
+			ctx.Next()
+			return
+		}
+
+		log.Printf("[minion][sync][middleware] rejecting %s request: state not synced from the leader yet", ctx.Request.URL.Path)
+		utils.SetContextAndExecJSONWithErrorResponse(ctx, utils.ErrStateNotSynced)
+		ctx.Abort()
+	}
+}
@@ -10,16 +10,41 @@ import (
 	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
 
-func InitMinion(ctx context.Context) func() {
+const sagaRetryInterval = 10 * time.Second
+
+func InitMinion(ctx context.Context, elector leaderelection.Elector) func() {
 	minionCtx, minionCancel := context.WithCancel(ctx)
 
 	integ := newIntegration()
 	repo := newRepository()
-	svc := newService(integ, repo)
+	if config.Configuration.IsCacheSnapshotEnabled() {
+		cache, err := newCacheFile(config.Configuration.GetDataDir())
+		if err != nil {
+			log.Fatalf("[minion][cache] failed to open cache snapshot: %v", err)
+		}
+
+		if err := repo.Restore(cache); err != nil {
+			log.Printf("[minion][cache] failed to restore cache snapshot, starting empty: %v", err)
+		}
+	}
+
+	sagas, err := newSagaLog(config.Configuration.GetDataDir())
+	if err != nil {
+		log.Fatalf("[minion][saga] failed to load saga log: %v", err)
+	}
+
+	results, err := idempotency.NewFileStore(config.Configuration.GetDataDir(), config.Configuration.GetIdempotencyTTL())
+	if err != nil {
+		log.Fatalf("[minion][idempotency] failed to load idempotency store: %v", err)
+	}
+
+	svc := newService(integ, repo, elector, sagas, results)
 
 	if err := bindAuditQueue(); err != nil {
 		log.Fatalf("[minion][audit] failed to bind audit queue: %v", err)
@@ -34,8 +59,14 @@ func InitMinion(ctx context.Context) func() {
 	}
 
 	go serve(server)
-	go healthcheck(minionCtx, svc)
+	if consensus.Enabled() {
+		go followLeader(minionCtx, svc)
+	} else {
+		go pullState(minionCtx, svc)
+		go healthcheck(minionCtx, svc)
+	}
 	go consumeBroker(minionCtx, svc)
+	go retryCompensations(minionCtx, svc)
 
 	return func() {
 		minionCancel()
@@ -59,7 +90,6 @@ func serve(server *http.Server) {
 
 func healthcheck(ctx context.Context, svc service) {
 	maxLeaderFailures := config.Configuration.GetMaxLeaderFailures()
-	failureCount := 0
 
 	for {
 		select {
@@ -67,17 +97,23 @@ func healthcheck(ctx context.Context, svc service) {
 			if err := svc.SendHealthCheck(ctx); err != nil {
 				log.Printf("[minion][healthcheck] failed to send healthcheck: %v", err)
 
+				if errors.Is(err, utils.ErrStaleTerm) {
+					svc.RefreshLeader(ctx)
+					svc.heartbeat.Reset()
+					continue
+				}
+
 				if errors.Is(err, utils.ErrLeaderUnreachable) {
-					failureCount++
+					failureCount := svc.heartbeat.Failed()
 
 					if failureCount == maxLeaderFailures {
-						go leaderelection.StartElection(svc.ListTowers())
-						failureCount = 0
+						go svc.StartElection()
+						svc.heartbeat.Reset()
 						time.Sleep(5 * time.Second)
 					}
 				}
 			} else {
-				failureCount = 0
+				svc.heartbeat.Succeeded()
 			}
 
 		case <-ctx.Done():
@@ -86,23 +122,73 @@ func healthcheck(ctx context.Context, svc service) {
 	}
 }
 
+// raft detects leader failures on its own
+func followLeader(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(config.Configuration.GetHeartbeatInterval()):
+			svc.RefreshLeader(ctx)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func pullState(ctx context.Context, svc service) {
+	for !svc.repository.IsSynced() {
+		err := svc.PullState(ctx)
+		if err == nil {
+			return
+		}
+
+		log.Printf("[minion][sync] failed to pull state snapshot: %v", err)
+		if errors.Is(err, utils.ErrStaleTerm) {
+			svc.RefreshLeader(ctx)
+		}
+
+		select {
+		case <-time.After(config.Configuration.GetHeartbeatInterval()):
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func retryCompensations(ctx context.Context, svc service) {
+	for {
+		select {
+		case <-time.After(sagaRetryInterval):
+			svc.RetryCompensations(ctx)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func consumeBroker(ctx context.Context, svc service) {
-	slotReleaseCh, err := bindTowersQueue()
+	vehicleEventCh, err := bindTowersQueue()
 	if err != nil {
 		log.Fatalf("[minion][consumer] failed to bind towers queue: %v", err)
 	}
 
 	for {
 		select {
-		case msg := <-slotReleaseCh:
+		case msg := <-vehicleEventCh:
 			log.Printf("[minion][consumer] received message: %s", string(msg.Body))
-			if err := svc.ReleaseSlot(ctx, msg.Body); err != nil {
-				log.Printf("[minion][consumer] failed to release slot: %v", err)
+			if err := svc.HandleVehicleEvent(ctx, msg.Body); err != nil {
+				log.Printf("[minion][consumer] failed to handle vehicle event: %v", err)
 			}
 
 		case <-ctx.Done():
 			log.Printf("[minion][consumer] interrupting consumer...")
-			config.CloseRabbitMQ()
+			// the channel stays open for the leader role, which publishes
+			// waitlist grants
+			if err := config.Configuration.GetRabbitMQChannel().Cancel(config.Configuration.GetIdAsString(), false); err != nil {
+				log.Printf("[minion][consumer] failed to cancel consumer: %v", err)
+			}
 			return
 		}
 	}
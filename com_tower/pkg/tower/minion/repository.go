@@ -1,33 +1,258 @@
 package minion
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
 
+// cacheState is never modified once stored: syncs build a new one and swap it
+// in, so readers never see a sync halfway through.
+type cacheState struct {
+	towers             []types.Tower
+	structures         types.Structures
+	towersByUUID       map[types.UUID]types.Tower
+	towersSyncedAt     time.Time
+	structuresSyncedAt time.Time
+	towersVersion      *types.StateVersion
+	structuresVersion  *types.StateVersion
+	restoredSavedAt    time.Time
+}
+
+func (c *cacheState) IsSynced() bool {
+	return !c.towersSyncedAt.IsZero() && !c.structuresSyncedAt.IsZero()
+}
+
+func (c *cacheState) Staleness() (stale bool, savedAt time.Time) {
+	return !c.IsSynced() && !c.restoredSavedAt.IsZero(), c.restoredSavedAt
+}
+
+func (c cacheState) withTowers(towers []types.Tower) *cacheState {
+	c.towers = towers
+	c.towersByUUID = byUUID(towers, func(tower types.Tower) types.UUID { return tower.UUID })
+	return &c
+}
+
+func (c cacheState) withStructures(structures types.Structures) *cacheState {
+	c.structures = structures
+	return &c
+}
+
+func byUUID[T any](items []T, id func(T) types.UUID) map[types.UUID]T {
+	indexed := make(map[types.UUID]T, len(items))
+	for _, item := range items {
+		indexed[id(item)] = item
+	}
+
+	return indexed
+}
+
 type repository struct {
-	towers []types.Tower
-	structures types.Structures
+	mu    sync.Mutex
+	state atomic.Pointer[cacheState]
+	cache *cacheFile
 }
 
 func newRepository() *repository {
-	return &repository{
-		towers: []types.Tower{},
-		structures: types.Structures{},
-	}
+	r := &repository{}
+	r.state.Store((&cacheState{}).withTowers([]types.Tower{}).withStructures(types.Structures{}))
+	return r
+}
+
+// Snapshot returns the current state. Callers reading more than one part of
+// the state read them from a single snapshot.
+func (r *repository) Snapshot() *cacheState {
+	return r.state.Load()
 }
 
 func (r *repository) ListTowers() []types.Tower {
-	return r.towers
+	return slices.Clone(r.Snapshot().towers)
 }
 
 func (r *repository) ListStructures() types.Structures {
-	return r.structures
+	structures := r.Snapshot().structures
+	return types.Structures{
+		Platforms: slices.Clone(structures.Platforms),
+		Centrals:  slices.Clone(structures.Centrals),
+	}
+}
+
+func (r *repository) GetTower(id types.UUID) (types.Tower, bool) {
+	tower, ok := r.Snapshot().towersByUUID[id]
+	return tower, ok
+}
+
+func (r *repository) Restore(cache *cacheFile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cache = cache
+
+	snapshot, err := cache.Load()
+	if err != nil || snapshot == nil {
+		return err
+	}
+
+	state := (&cacheState{}).withTowers(snapshot.Towers).withStructures(snapshot.Structures)
+	state.towersVersion = snapshot.TowersVersion
+	state.structuresVersion = snapshot.StructuresVersion
+	state.restoredSavedAt = snapshot.SavedAt
+	r.state.Store(state)
+	return nil
+}
+
+// persist is called with mu held, so snapshots are saved in the order they
+// were synced.
+func (r *repository) persist(state *cacheState) {
+	if r.cache == nil {
+		return
+	}
+
+	snapshot := types.CacheSnapshot{
+		SavedAt:           time.Now(),
+		Towers:            state.towers,
+		Structures:        state.structures,
+		TowersVersion:     state.towersVersion,
+		StructuresVersion: state.structuresVersion,
+	}
+
+	if err := r.cache.Save(snapshot); err != nil {
+		log.Printf("[minion][cache] failed to save cache snapshot: %v", err)
+	}
+}
+
+func (r *repository) IsSynced() bool {
+	return r.Snapshot().IsSynced()
 }
 
-func (r *repository) SyncTowers(towers types.TowersPayload) {
-	r.towers = towers.Towers
+func (r *repository) GetVersions() (towers *types.StateVersion, structures *types.StateVersion) {
+	state := r.Snapshot()
+	return state.towersVersion, state.structuresVersion
 }
 
-func (r *repository) SyncStructures(structures types.Structures) {
-	r.structures = structures
+// checkVersion refuses payloads older than the version held and deltas built
+// on top of another version.
+func checkVersion(held *types.StateVersion, version *types.StateVersion, base *types.StateVersion) error {
+	if version == nil {
+		return nil
+	}
+
+	if held != nil && version.Before(*held) {
+		return fmt.Errorf("payload version %s is older than the version held %s: %w", version.String(), held.String(), utils.ErrOutOfOrderState)
+	}
+
+	if base != nil && (held == nil || *held != *base) {
+		return fmt.Errorf("delta from version %s does not apply to the version held: %w", base.String(), utils.ErrOutOfOrderState)
+	}
+
+	return nil
+}
+
+func upsert[T any](items []T, changed []T, removed []types.UUID, id func(T) types.UUID) []T {
+	result := slices.DeleteFunc(slices.Clone(items), func(item T) bool {
+		return slices.Contains(removed, id(item)) || slices.ContainsFunc(changed, func(other T) bool { return id(other) == id(item) })
+	})
+
+	return append(result, changed...)
+}
+
+func syncTowers(state *cacheState, towers types.TowersPayload) (*cacheState, error) {
+	if err := checkVersion(state.towersVersion, towers.Version, towers.Base); err != nil {
+		return nil, err
+	}
+
+	next := towers.Towers
+	if towers.Base != nil {
+		next = upsert(state.towers, towers.Towers, towers.Removed, func(tower types.Tower) types.UUID { return tower.UUID })
+	}
+
+	synced := state.withTowers(slices.Clone(next))
+	synced.towersVersion = towers.Version
+	synced.towersSyncedAt = time.Now()
+	return synced, nil
+}
+
+func syncStructures(state *cacheState, structures types.StructuresPayload) (*cacheState, error) {
+	if err := checkVersion(state.structuresVersion, structures.Version, structures.Base); err != nil {
+		return nil, err
+	}
+
+	next := types.Structures{
+		Platforms: slices.Clone(structures.Platforms),
+		Centrals:  slices.Clone(structures.Centrals),
+	}
+
+	if structures.Base != nil {
+		next = types.Structures{
+			Platforms: upsert(state.structures.Platforms, structures.Platforms, structures.RemovedPlatforms, func(platform types.Platform) types.UUID { return platform.UUID }),
+			Centrals:  upsert(state.structures.Centrals, structures.Centrals, structures.RemovedCentrals, func(central types.Central) types.UUID { return central.UUID }),
+		}
+	}
+
+	synced := state.withStructures(next)
+	synced.structuresVersion = structures.Version
+	synced.structuresSyncedAt = time.Now()
+	return synced, nil
+}
+
+func (r *repository) SyncTowers(towers types.TowersPayload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := syncTowers(r.Snapshot(), towers)
+	if err != nil {
+		return err
+	}
+
+	r.state.Store(state)
+	r.persist(state)
+	return nil
+}
+
+func (r *repository) SyncStructures(structures types.StructuresPayload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, err := syncStructures(r.Snapshot(), structures)
+	if err != nil {
+		return err
+	}
+
+	r.state.Store(state)
+	r.persist(state)
+	return nil
+}
+
+// SyncSnapshot keeps the towers or structures a propagation already brought
+// to a newer version.
+func (r *repository) SyncSnapshot(snapshot types.StateSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version := snapshot.Version
+	state := r.Snapshot()
+
+	synced, towersErr := syncTowers(state, types.TowersPayload{Towers: snapshot.Towers, Version: &version})
+	if towersErr == nil {
+		state = synced
+	}
+
+	synced, structuresErr := syncStructures(state, types.StructuresPayload{Structures: snapshot.Structures, Version: &version})
+	if structuresErr == nil {
+		state = synced
+	}
+
+	if towersErr == nil || structuresErr == nil {
+		r.state.Store(state)
+		r.persist(state)
+	}
+
+	return errors.Join(towersErr, structuresErr)
 }
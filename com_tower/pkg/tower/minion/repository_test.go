@@ -0,0 +1,254 @@
+package minion
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
+	"github.com/google/uuid"
+)
+
+const syncedVersions = 200
+
+// versionedState builds the towers and structures of a version, tagging each
+// of them with the version in its latitude so readers can tell which version
+// they come from.
+type versionedState struct {
+	towers    []types.UUID
+	platforms []types.UUID
+	centrals  []types.UUID
+}
+
+func newVersionedState() versionedState {
+	state := versionedState{}
+	for range 3 {
+		state.towers = append(state.towers, types.UUID(uuid.New()))
+		state.platforms = append(state.platforms, types.UUID(uuid.New()))
+		state.centrals = append(state.centrals, types.UUID(uuid.New()))
+	}
+
+	return state
+}
+
+func (v versionedState) Towers(seq int64) []types.Tower {
+	towers := make([]types.Tower, 0, len(v.towers))
+	for _, id := range v.towers {
+		towers = append(towers, types.Tower{UUID: id, Latitude: float64(seq)})
+	}
+
+	return towers
+}
+
+func (v versionedState) Structures(seq int64) types.Structures {
+	structures := types.Structures{}
+	for _, id := range v.platforms {
+		structures.Platforms = append(structures.Platforms, types.Platform{UUID: id, Structure: types.Structure{Latitude: float64(seq)}})
+	}
+
+	for _, id := range v.centrals {
+		structures.Centrals = append(structures.Centrals, types.Central{UUID: id, Structure: types.Structure{Latitude: float64(seq)}})
+	}
+
+	return structures
+}
+
+// towersSeq returns the version the towers were tagged with, failing when
+// they come from more than one version.
+func towersSeq(t *testing.T, towers []types.Tower) int64 {
+	t.Helper()
+
+	if len(towers) == 0 {
+		return 0
+	}
+
+	seq := int64(towers[0].Latitude)
+	for _, tower := range towers {
+		if int64(tower.Latitude) != seq {
+			t.Errorf("towers mix versions %d and %d", seq, int64(tower.Latitude))
+		}
+	}
+
+	return seq
+}
+
+// structuresSeq returns the version the structures were tagged with, failing
+// when they come from more than one version.
+func structuresSeq(t *testing.T, structures types.Structures) int64 {
+	t.Helper()
+
+	var seqs []int64
+	for _, platform := range structures.Platforms {
+		seqs = append(seqs, int64(platform.Latitude))
+	}
+
+	for _, central := range structures.Centrals {
+		seqs = append(seqs, int64(central.Latitude))
+	}
+
+	if len(seqs) == 0 {
+		return 0
+	}
+
+	for _, seq := range seqs {
+		if seq != seqs[0] {
+			t.Errorf("structures mix versions %d and %d", seqs[0], seq)
+		}
+	}
+
+	return seqs[0]
+}
+
+func seqOf(version *types.StateVersion) int64 {
+	if version == nil {
+		return 0
+	}
+
+	return version.Seq
+}
+
+func TestRepositoryReadersSeeTowersAndStructuresOfOneVersion(t *testing.T) {
+	repo := newRepository()
+	versions := newVersionedState()
+
+	done := make(chan struct{})
+	var writers sync.WaitGroup
+
+	// the snapshots move both halves forward together
+	writers.Go(func() {
+		for seq := int64(1); seq <= syncedVersions; seq++ {
+			snapshot := types.StateSnapshot{
+				Version:    types.StateVersion{Term: 1, Seq: seq},
+				Towers:     versions.Towers(seq),
+				Structures: versions.Structures(seq),
+			}
+
+			if err := repo.SyncSnapshot(snapshot); err != nil {
+				t.Errorf("failed to sync snapshot %d: %v", seq, err)
+			}
+		}
+	})
+
+	// propagations of the version already held race with the snapshots, and
+	// are refused once a snapshot moved past them
+	resync := func(held func() *types.StateVersion, apply func(version types.StateVersion) error) func() {
+		return func() {
+			for range syncedVersions {
+				version := held()
+				if version == nil {
+					continue
+				}
+
+				if err := apply(*version); err != nil && !errors.Is(err, utils.ErrOutOfOrderState) {
+					t.Errorf("failed to resync version %d: %v", version.Seq, err)
+				}
+			}
+		}
+	}
+
+	writers.Go(resync(func() *types.StateVersion {
+		towers, _ := repo.GetVersions()
+		return towers
+	}, func(version types.StateVersion) error {
+		return repo.SyncTowers(types.TowersPayload{Towers: versions.Towers(version.Seq), Version: &version})
+	}))
+
+	writers.Go(resync(func() *types.StateVersion {
+		_, structures := repo.GetVersions()
+		return structures
+	}, func(version types.StateVersion) error {
+		return repo.SyncStructures(types.StructuresPayload{Structures: versions.Structures(version.Seq), Version: &version})
+	}))
+
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Go(func() {
+			var last int64
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				state := repo.Snapshot()
+				towers, structures := towersSeq(t, state.towers), structuresSeq(t, state.structures)
+				if towers != structures || towers != seqOf(state.towersVersion) || structures != seqOf(state.structuresVersion) {
+					t.Errorf("snapshot holds towers of version %d and structures of version %d, versioned %d and %d", towers, structures, seqOf(state.towersVersion), seqOf(state.structuresVersion))
+					return
+				}
+
+				if towers < last {
+					t.Errorf("snapshot went back from version %d to %d", last, towers)
+					return
+				}
+				last = towers
+
+				for _, id := range versions.towers {
+					if tower, ok := state.towersByUUID[id]; towers > 0 && (!ok || int64(tower.Latitude) != towers) {
+						t.Errorf("tower %s is not indexed at version %d", id.String(), towers)
+						return
+					}
+				}
+			}
+		})
+
+		readers.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				towersSeq(t, repo.ListTowers())
+				structuresSeq(t, repo.ListStructures())
+
+				if tower, ok := repo.GetTower(versions.towers[0]); ok && tower.UUID != versions.towers[0] {
+					t.Errorf("looked up tower %s, got %s", versions.towers[0].String(), tower.UUID.String())
+					return
+				}
+			}
+		})
+	}
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	state := repo.Snapshot()
+	if seqOf(state.towersVersion) != syncedVersions || seqOf(state.structuresVersion) != syncedVersions {
+		t.Fatalf("repository holds versions %d and %d, want %d", seqOf(state.towersVersion), seqOf(state.structuresVersion), syncedVersions)
+	}
+}
+
+func TestRepositoryListsCannotModifyTheState(t *testing.T) {
+	repo := newRepository()
+	versions := newVersionedState()
+
+	snapshot := types.StateSnapshot{
+		Version:    types.StateVersion{Term: 1, Seq: 1},
+		Towers:     versions.Towers(1),
+		Structures: versions.Structures(1),
+	}
+
+	if err := repo.SyncSnapshot(snapshot); err != nil {
+		t.Fatalf("failed to sync snapshot: %v", err)
+	}
+
+	towers := repo.ListTowers()
+	towers[0].Latitude = 2
+
+	structures := repo.ListStructures()
+	structures.Platforms[0].Latitude = 2
+	structures.Centrals[0].Latitude = 2
+
+	if seq := towersSeq(t, repo.ListTowers()); seq != 1 {
+		t.Fatalf("towers hold version %d after modifying a listed copy, want 1", seq)
+	}
+
+	if seq := structuresSeq(t, repo.ListStructures()); seq != 1 {
+		t.Fatalf("structures hold version %d after modifying a listed copy, want 1", seq)
+	}
+}
@@ -1,28 +1,49 @@
 package minion
 
 import (
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
 	"github.com/gin-gonic/gin"
 )
 
 func setupRouter(svc service) (router *gin.Engine) {
 	handler := newHandler(svc)
+	idempotent := idempotency.Middleware(svc.results)
+	synced := RequireSyncedState(svc)
 
 	router = gin.Default()
 	router.Use(AuditRequests())
-	router.GET("towers", handler.ListTowers)
-	router.GET("towers/", handler.ListTowers)
-	router.POST("towers", handler.SyncTowers)
-	router.POST("towers/", handler.SyncTowers)
-	router.GET("structures", handler.ListStructures)
-	router.GET("structures/", handler.ListStructures)
-	router.POST("structures", handler.SyncStructures)
-	router.POST("structures/", handler.SyncStructures)
-	router.POST("slots", handler.CheckSlotAvailability)
-	router.POST("slots/", handler.CheckSlotAvailability)
+	router.GET("towers", synced, handler.ListTowers)
+	router.GET("towers/", synced, handler.ListTowers)
+	router.POST("towers", RejectStaleTerm(svc), handler.SyncTowers)
+	router.POST("towers/", RejectStaleTerm(svc), handler.SyncTowers)
+	router.GET("structures", synced, handler.ListStructures)
+	router.GET("structures/", synced, handler.ListStructures)
+	router.POST("structures", RejectStaleTerm(svc), handler.SyncStructures)
+	router.POST("structures/", RejectStaleTerm(svc), handler.SyncStructures)
+	router.POST("slots", synced, idempotent, handler.CheckSlotAvailability)
+	router.POST("slots/", synced, idempotent, handler.CheckSlotAvailability)
+	router.POST("slots/assign", synced, handler.AssignSlot)
+	router.POST("slots/assign/", synced, handler.AssignSlot)
+	router.POST("waitlist", handler.EnqueueWaitlist)
+	router.POST("waitlist/", handler.EnqueueWaitlist)
+	router.POST("waitlist/leave", handler.LeaveWaitlist)
+	router.POST("waitlist/leave/", handler.LeaveWaitlist)
+	router.GET("bookings", handler.ListBookings)
+	router.GET("bookings/", handler.ListBookings)
+	router.POST("bookings", handler.CreateBooking)
+	router.POST("bookings/", handler.CreateBooking)
+	router.POST("bookings/cancel", handler.CancelBooking)
+	router.POST("bookings/cancel/", handler.CancelBooking)
+	router.GET("sagas", handler.ListSagas)
+	router.GET("sagas/", handler.ListSagas)
 	router.POST("election", handler.HandleElection)
 	router.POST("election/", handler.HandleElection)
+	router.POST("pre-vote", handler.HandlePreVote)
+	router.POST("pre-vote/", handler.HandlePreVote)
 	router.POST("leader", handler.SetNewLeader)
 	router.POST("leader/", handler.SetNewLeader)
+	router.GET("cluster", handler.GetClusterStatus)
+	router.GET("cluster/", handler.GetClusterStatus)
 
 	return
 }
@@ -0,0 +1,154 @@
+package minion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+	"github.com/google/uuid"
+)
+
+const sagaLogFile = "sagas.json"
+
+// sagaLog is written to disk on every step, so compensations survive restarts.
+type sagaLog struct {
+	mu    sync.Mutex
+	path  string
+	sagas map[string]types.SlotSaga
+}
+
+// sagas still reserved when the tower stopped are in doubt: the leader may or
+// may not have acquired them
+func newSagaLog(dir string) (*sagaLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create saga log dir: %w", err)
+	}
+
+	l := &sagaLog{
+		path:  filepath.Join(dir, sagaLogFile),
+		sagas: map[string]types.SlotSaga{},
+	}
+
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saga log: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &l.sagas); err != nil {
+		return nil, fmt.Errorf("failed to decode saga log: %w", err)
+	}
+
+	for id, saga := range l.sagas {
+		if saga.Step == types.ReservedSagaStep {
+			saga.Step = types.InDoubtSagaStep
+			l.sagas[id] = saga
+		}
+	}
+
+	return l, nil
+}
+
+func (l *sagaLog) Begin(request types.SlotRequest) (types.SlotSaga, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	saga := types.SlotSaga{
+		ID:            uuid.NewString(),
+		VehicleUUID:   request.VehicleUUID,
+		StructureUUID: request.StructureUUID,
+		StructureType: request.StructureType,
+		SlotType:      request.SlotType,
+		SlotNumber:    request.SlotNumber,
+		Step:          types.ReservedSagaStep,
+		StartedAt:     time.Now(),
+	}
+
+	l.sagas[saga.ID] = saga
+	if err := l.persist(); err != nil {
+		delete(l.sagas, saga.ID)
+		return types.SlotSaga{}, err
+	}
+
+	return saga, nil
+}
+
+func (l *sagaLog) Compensate(id string, cause error) error {
+	return l.update(id, func(saga *types.SlotSaga) {
+		saga.Step = types.CompensatingSagaStep
+		saga.LastError = cause.Error()
+	})
+}
+
+func (l *sagaLog) Failed(id string, err error) error {
+	return l.update(id, func(saga *types.SlotSaga) {
+		saga.Attempts++
+		saga.LastError = err.Error()
+	})
+}
+
+func (l *sagaLog) Complete(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.sagas[id]; !ok {
+		return nil
+	}
+
+	delete(l.sagas, id)
+	return l.persist()
+}
+
+func (l *sagaLog) List() []types.SlotSaga {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sagas := make([]types.SlotSaga, 0, len(l.sagas))
+	for _, saga := range l.sagas {
+		sagas = append(sagas, saga)
+	}
+
+	slices.SortFunc(sagas, func(a, b types.SlotSaga) int { return a.StartedAt.Compare(b.StartedAt) })
+	return sagas
+}
+
+func (l *sagaLog) update(id string, apply func(saga *types.SlotSaga)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	saga, ok := l.sagas[id]
+	if !ok {
+		return fmt.Errorf("saga %s not found", id)
+	}
+
+	apply(&saga)
+	l.sagas[id] = saga
+	return l.persist()
+}
+
+func (l *sagaLog) persist() error {
+	data, err := json.Marshal(l.sagas)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga log: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write saga log: %w", err)
+	}
+
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to replace saga log: %w", err)
+	}
+
+	return nil
+}
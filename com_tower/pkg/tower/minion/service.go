@@ -6,42 +6,110 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"slices"
 	"time"
 
 	"github.com/ViniiSouza/maritime_flow/com_tower/config"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/consensus"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/idempotency"
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/leaderelection"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
 	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/utils"
 )
 
+const maxAssignAttempts = 3
+
+const structureReleaseStep = "structure"
+
 type service struct {
 	integration integration
 	repository  *repository
+	elector     leaderelection.Elector
+	heartbeat   *heartbeat
+	results     *idempotency.MemoryStore
+	sagas       *sagaLog
+	lag         *stateLag
 }
 
-func newService(i integration, r *repository) service {
+func newService(i integration, r *repository, e leaderelection.Elector, l *sagaLog, results *idempotency.MemoryStore) service {
 	return service{
 		integration: i,
 		repository:  r,
+		elector:     e,
+		sagas:       l,
+		heartbeat:   newHeartbeat(),
+		lag:         newStateLag(),
+		results:     results,
 	}
 }
 
 func (s service) ListTowers() []types.Tower {
+	if consensus.Enabled() {
+		return consensus.Replica.ListTowers()
+	}
+
 	return s.repository.ListTowers()
 }
 
 func (s service) ListStructures() types.Structures {
+	if consensus.Enabled() {
+		return consensus.Replica.ListStructures()
+	}
+
 	return s.repository.ListStructures()
 }
 
-func (s service) SyncTowers(towers types.TowersPayload) {
-	s.repository.SyncTowers(towers)
+func (s service) GetClusterStatus() types.ClusterStatus {
+	// the towers, their versions and sync times come from the same snapshot
+	state := s.repository.Snapshot()
+	towers := slices.Clone(state.towers)
+	if consensus.Enabled() {
+		towers = consensus.Replica.ListTowers()
+	}
+
+	status := types.ClusterStatus{
+		TowerUUID:   config.Configuration.GetId(),
+		Role:        types.Minion.String(),
+		LeaderUUID:  config.Configuration.GetLeaderUUID(),
+		LeaderSince: config.Configuration.GetLeaderSince(),
+		Term:        config.Configuration.GetLeaderTerm(),
+		Towers:      towers,
+	}
+
+	lastHeartbeat, failureCount := s.heartbeat.Status()
+	if !lastHeartbeat.IsZero() {
+		status.LastHeartbeatAt = &lastHeartbeat
+	}
+	status.HeartbeatFailures = failureCount
+
+	if syncedAt := state.towersSyncedAt; !syncedAt.IsZero() {
+		status.TowersSyncedAt = &syncedAt
+	}
+
+	status.TowersVersion, status.StructuresVersion = state.towersVersion, state.structuresVersion
+
+	if stale, savedAt := state.Staleness(); stale {
+		status.StateStale = true
+		status.StateSavedAt = &savedAt
+	}
+
+	return status
+}
+
+func (s service) SyncTowers(towers types.TowersPayload) error {
+	return s.repository.SyncTowers(towers)
 }
 
-func (s service) SyncStructures(structures types.Structures) {
-	s.repository.SyncStructures(structures)
+func (s service) SyncStructures(structures types.StructuresPayload) error {
+	return s.repository.SyncStructures(structures)
 }
 
-func (s service) CheckSlotAvailability(ctx context.Context, request types.SlotRequest) (result *types.SlotResponse, err error) {
+func (s service) CheckSlotAvailability(ctx context.Context, request types.SlotRequest, idempotencyKey string) (result *types.SlotResponse, err error) {
+	if !request.Priority.IsValid() {
+		return nil, fmt.Errorf("unknown priority %s: %w", request.Priority, utils.ErrInvalidInput)
+	}
+
 	failureCount := 0
 	
 	for (failureCount < config.Configuration.GetMaxStructureFailures()) {
@@ -69,21 +137,54 @@ func (s service) CheckSlotAvailability(ctx context.Context, request types.SlotRe
 		}, nil
 	}
 
+	// emergency vehicles take over slots reserved for vehicles that have not
+	// arrived yet
+	if result.State == types.InUseSlotState && request.Priority == types.EmergencySlotPriority {
+		return s.preemptSlot(ctx, request)
+	}
+
 	if result.State == types.FreeSlotState {
+		// the slot is taken in the structure from now on: the saga makes sure
+		// it is released there if the leader does not grant it
+		saga, err := s.sagas.Begin(request)
+		if err != nil {
+			releaseSlotReq := types.ReleaseSlotRequest{SlotNumber: request.SlotNumber, SlotType: request.SlotType}
+			if err := s.integration.ReleaseSlot(ctx, request.StructureUUID, request.StructureType, releaseSlotReq); err != nil {
+				log.Printf("failed to rollback slot request in %s %s: %v", request.StructureType, request.StructureUUID.String(), err)
+			}
+
+			return nil, fmt.Errorf("failed to log slot saga: %w", err)
+		}
+
 		acquireRequest := types.AcquireSlotRequest{
 			VehicleUUID:          request.VehicleUUID,
 			StructureUUID:        request.StructureUUID,
+			Priority:             request.Priority,
 			StructureSlotRequest: request.StructureSlotRequest,
 		}
 
-		acquireResult, err := s.integration.AcquireSlotLockInTowerLeader(ctx, acquireRequest)
+		acquireResult, err := s.integration.AcquireSlotLockInTowerLeader(ctx, acquireRequest, idempotencyKey)
+		if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+			acquireResult, err = s.integration.AcquireSlotLockInTowerLeader(ctx, acquireRequest, idempotencyKey)
+		}
+
+		// another acquisition raced this one: the retry either gets the slot
+		// or finds it taken, so it must not replay the conflict
+		if err == nil && acquireResult.Result == types.ConflictAcquireSlotResultType {
+			log.Printf("acquisition of %s %d in structure %s conflicted with another acquisition, retrying", request.SlotType, request.SlotNumber, request.StructureUUID.String())
+			acquireResult, err = s.integration.AcquireSlotLockInTowerLeader(ctx, acquireRequest, "")
+		}
+
 		if err != nil {
 			log.Printf("failed to request slot to tower leader: %v", err)
-			
-			// rollback slot request in structure
-			releaseSlotReq := types.ReleaseSlotRequest{SlotNumber: request.SlotNumber, SlotType: request.SlotType}
-			if err := s.integration.ReleaseSlot(ctx, request.StructureUUID, request.StructureType, releaseSlotReq); err != nil {
-				return nil, fmt.Errorf("failed to rollback slot request in %s %s: %w", request.StructureType, request.StructureUUID.String(), err)
+			s.compensate(ctx, saga, err)
+
+			// the leader cannot grant slots right now: vehicles hold position
+			// instead of looking for another slot
+			if errors.Is(err, utils.ErrLeaderReadOnly) {
+				return &types.SlotResponse{
+					State: types.HoldSlotState,
+				}, nil
 			}
 
 			return &types.SlotResponse{
@@ -91,7 +192,15 @@ func (s service) CheckSlotAvailability(ctx context.Context, request types.SlotRe
 			}, nil
 		}
 
-		if acquireResult.Result == types.UnavailableAcquireSlotResultType {
+		// the slot is kept for a waitlisted vehicle, which the leader requests
+		// from the structure itself
+		if acquireResult.Result == types.WaitlistedAcquireSlotResultType {
+			s.compensate(ctx, saga, errors.New("slot is kept for a waitlisted vehicle"))
+		} else if err := s.sagas.Complete(saga.ID); err != nil {
+			log.Printf("[minion][saga] failed to complete saga %s: %v", saga.ID, err)
+		}
+
+		if acquireResult.Result != types.AcquiredAcquireSlotResultType {
 			return &types.SlotResponse{
 				State: types.InUseSlotState,
 			}, nil
@@ -101,23 +210,381 @@ func (s service) CheckSlotAvailability(ctx context.Context, request types.SlotRe
 	return result, nil
 }
 
+// compensate leaves the releases that fail in the saga log, to be retried in
+// the background.
+func (s service) compensate(ctx context.Context, saga types.SlotSaga, cause error) {
+	if err := s.sagas.Compensate(saga.ID, cause); err != nil {
+		log.Printf("[minion][saga] failed to log compensation of saga %s: %v", saga.ID, err)
+	}
+
+	s.releaseSagaSlot(ctx, saga)
+}
+
+func (s service) releaseSagaSlot(ctx context.Context, saga types.SlotSaga) {
+	releaseSlotReq := types.ReleaseSlotRequest{SlotNumber: saga.SlotNumber, SlotType: saga.SlotType}
+	if err := s.integration.ReleaseSlot(ctx, saga.StructureUUID, saga.StructureType, releaseSlotReq); err != nil {
+		log.Printf("[minion][saga] failed to release %s %d in %s %s for saga %s, retrying later: %v", saga.SlotType, saga.SlotNumber, saga.StructureType, saga.StructureUUID.String(), saga.ID, err)
+		if err := s.sagas.Failed(saga.ID, err); err != nil {
+			log.Printf("[minion][saga] failed to log failed compensation of saga %s: %v", saga.ID, err)
+		}
+		return
+	}
+
+	if err := s.sagas.Complete(saga.ID); err != nil {
+		log.Printf("[minion][saga] failed to complete saga %s: %v", saga.ID, err)
+	}
+}
+
+// RetryCompensations also settles the sagas left in doubt by a restart: a
+// slot the leader reports as free was never acquired and is released in the
+// structure.
+func (s service) RetryCompensations(ctx context.Context) {
+	for _, saga := range s.sagas.List() {
+		switch saga.Step {
+		case types.CompensatingSagaStep:
+			s.releaseSagaSlot(ctx, saga)
+
+		case types.InDoubtSagaStep:
+			slotsRequest := types.FreeSlotsRequest{StructureUUID: saga.StructureUUID, SlotType: saga.SlotType}
+			freeSlots, err := s.integration.ListFreeSlotsInTowerLeader(ctx, slotsRequest)
+			if err != nil {
+				log.Printf("[minion][saga] failed to check saga %s in tower leader: %v", saga.ID, err)
+				continue
+			}
+
+			if !slices.Contains(freeSlots.SlotNumbers, saga.SlotNumber) {
+				if err := s.sagas.Complete(saga.ID); err != nil {
+					log.Printf("[minion][saga] failed to complete saga %s: %v", saga.ID, err)
+				}
+				continue
+			}
+
+			s.compensate(ctx, saga, errors.New("slot was not acquired in the leader before the tower stopped"))
+		}
+	}
+}
+
+func (s service) ListSagas() types.SagasResponse {
+	return types.SagasResponse{Sagas: s.sagas.List()}
+}
+
+func (s service) preemptSlot(ctx context.Context, request types.SlotRequest) (*types.SlotResponse, error) {
+	preemptRequest := types.AcquireSlotRequest{
+		VehicleUUID:          request.VehicleUUID,
+		StructureUUID:        request.StructureUUID,
+		Priority:             request.Priority,
+		StructureSlotRequest: request.StructureSlotRequest,
+	}
+
+	preemptResult, err := s.integration.PreemptSlotInTowerLeader(ctx, preemptRequest)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		preemptResult, err = s.integration.PreemptSlotInTowerLeader(ctx, preemptRequest)
+	}
+
+	if err == nil && preemptResult.Result == types.ConflictAcquireSlotResultType {
+		log.Printf("preemption of %s %d in structure %s conflicted with another acquisition, retrying", request.SlotType, request.SlotNumber, request.StructureUUID.String())
+		preemptResult, err = s.integration.PreemptSlotInTowerLeader(ctx, preemptRequest)
+	}
+
+	if errors.Is(err, utils.ErrLeaderReadOnly) {
+		return &types.SlotResponse{
+			State: types.HoldSlotState,
+		}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to preempt slot in tower leader: %w", err)
+	}
+
+	if preemptResult.Result != types.AcquiredAcquireSlotResultType {
+		return &types.SlotResponse{
+			State: types.InUseSlotState,
+		}, nil
+	}
+
+	return &types.SlotResponse{
+		State: types.FreeSlotState,
+	}, nil
+}
+
+func (s service) EnqueueWaitlist(ctx context.Context, request types.WaitlistRequest) (*types.WaitlistResponse, error) {
+	response, err := s.integration.EnqueueWaitlistInTowerLeader(ctx, request)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		response, err = s.integration.EnqueueWaitlistInTowerLeader(ctx, request)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue vehicle in tower leader: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s service) LeaveWaitlist(ctx context.Context, request types.LeaveWaitlistRequest) error {
+	err := s.integration.LeaveWaitlistInTowerLeader(ctx, request)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		err = s.integration.LeaveWaitlistInTowerLeader(ctx, request)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to remove vehicle from waitlist in tower leader: %w", err)
+	}
+
+	return nil
+}
+
+func (s service) CreateBooking(ctx context.Context, request types.BookingRequest) (*types.BookingResponse, error) {
+	response, err := s.integration.CreateBookingInTowerLeader(ctx, request)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		response, err = s.integration.CreateBookingInTowerLeader(ctx, request)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create booking in tower leader: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s service) CancelBooking(ctx context.Context, request types.CancelBookingRequest) error {
+	err := s.integration.CancelBookingInTowerLeader(ctx, request)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		err = s.integration.CancelBookingInTowerLeader(ctx, request)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to cancel booking in tower leader: %w", err)
+	}
+
+	return nil
+}
+
+func (s service) ListBookings(ctx context.Context, query types.BookingsQuery) (*types.BookingsResponse, error) {
+	response, err := s.integration.ListBookingsInTowerLeader(ctx, query)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		response, err = s.integration.ListBookingsInTowerLeader(ctx, query)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookings in tower leader: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s service) AssignSlot(ctx context.Context, request types.AssignSlotRequest) (*types.SlotResponse, error) {
+	slotType := types.GetSlotTypeByVehicleType(request.VehicleType)
+	if slotType == "" {
+		return nil, fmt.Errorf("unknown vehicle type %s: %w", request.VehicleType, utils.ErrInvalidInput)
+	}
+
+	slotsRequest := types.FreeSlotsRequest{StructureUUID: request.StructureUUID, SlotType: slotType}
+	freeSlots, err := s.integration.ListFreeSlotsInTowerLeader(ctx, slotsRequest)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		freeSlots, err = s.integration.ListFreeSlotsInTowerLeader(ctx, slotsRequest)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list free slots in tower leader: %w", err)
+	}
+
+	for i, slotNumber := range freeSlots.SlotNumbers {
+		if i == maxAssignAttempts {
+			break
+		}
+
+		result, err := s.CheckSlotAvailability(ctx, types.SlotRequest{
+			VehicleUUID:   request.VehicleUUID,
+			VehicleType:   request.VehicleType,
+			StructureUUID: request.StructureUUID,
+			StructureType: request.StructureType,
+			Priority:      request.Priority,
+			StructureSlotRequest: types.StructureSlotRequest{
+				SlotNumber: slotNumber,
+				SlotType:   slotType,
+			},
+		}, "")
+		if err != nil {
+			return nil, err
+		}
+
+		switch result.State {
+		case types.FreeSlotState:
+			return &types.SlotResponse{State: types.FreeSlotState, SlotNumber: slotNumber}, nil
+		case types.HoldSlotState:
+			return result, nil
+		}
+
+		log.Printf("%s %d in %s %s was taken, trying the next free slot", slotType, slotNumber, request.StructureType, request.StructureUUID.String())
+	}
+
+	return &types.SlotResponse{
+		State: types.InUseSlotState,
+	}, nil
+}
+
+// StartElection waits up to one heartbeat interval first, so towers that lost
+// the leader together do not all campaign at once.
+func (s service) StartElection() {
+	if interval := config.Configuration.GetHeartbeatInterval(); interval > 0 {
+		time.Sleep(rand.N(interval))
+	}
+
+	if _, err := s.elector.Campaign(context.Background(), s.ListTowers()); err != nil {
+		log.Printf("[minion][election] failed to run election: %v", err)
+	}
+}
+
+// IsLeaderReachable goes by the last healthcheck, as the heartbeat timeout may
+// outlast the failures that trigger an election.
+func (s service) IsLeaderReachable() bool {
+	return s.heartbeat.Reachable(config.Configuration.GetHeartbeatTimeout())
+}
+
+func (s service) TakeOverLeadership() {
+	won, err := s.elector.Campaign(context.Background(), nil)
+	if err != nil {
+		log.Printf("[minion][election] failed to take over leadership: %v", err)
+		return
+	}
+
+	if !won {
+		log.Printf("[minion][election] failed to take over leadership: leadership is held by another tower")
+	}
+}
+
 func (s service) SendHealthCheck(ctx context.Context) error {
-	return s.integration.SendHealthCheck(ctx)
+	towersVersion, structuresVersion := s.repository.GetVersions()
+	request := types.TowerHealthRequest{
+		Id:                config.Configuration.GetId(),
+		TowersVersion:     towersVersion,
+		StructuresVersion: structuresVersion,
+	}
+
+	response, err := s.integration.SendHealthCheck(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	lagging := lagsBehind(towersVersion, response.Version) || lagsBehind(structuresVersion, response.Version)
+	grace := config.Configuration.GetPropagationInterval() + config.Configuration.GetPropagationTimeout()
+	if s.lag.Observe(lagging, grace) {
+		log.Printf("[minion][sync] state held lags behind version %s of the leader, pulling snapshot", response.Version.String())
+		if err := s.PullState(ctx); err != nil {
+			log.Printf("[minion][sync] failed to pull state snapshot: %v", err)
+		}
+	}
+
+	return nil
 }
 
-func (s service) ReleaseSlot(ctx context.Context, data []byte) error {
+func (s service) PullState(ctx context.Context) error {
+	snapshot, err := s.integration.GetStateSnapshotFromTowerLeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.SyncSnapshot(*snapshot); err != nil && !errors.Is(err, utils.ErrOutOfOrderState) {
+		return fmt.Errorf("failed to apply state snapshot: %w", err)
+	}
+
+	s.lag.Reset()
+	log.Printf("[minion][sync] pulled state snapshot at version %s", snapshot.Version.String())
+	return nil
+}
+
+func (s service) HandleVehicleEvent(ctx context.Context, data []byte) error {
 	var msg types.VehicleEventMessage
 	if err := json.Unmarshal(data, &msg); err != nil {
 		return fmt.Errorf("failed to unmarshal vehicle message: %w", err)
 	}
 
+	if s.eventHandled(ctx, msg, "") {
+		log.Printf("[minion][consumer] skipping %s event of vehicle %s already handled with key %s", msg.Event, msg.VehicleUUID.String(), msg.IdempotencyKey)
+		return nil
+	}
+
+	var err error
+	switch msg.Event {
+	case types.ArrivalEventType:
+		err = s.OccupySlot(ctx, msg)
+	case types.DepartureEventType:
+		err = s.ReleaseSlot(ctx, msg)
+	default:
+		return fmt.Errorf("unknown vehicle event %s: %w", msg.Event, utils.ErrInvalidInput)
+	}
+
+	if err == nil {
+		err = s.markEventHandled(ctx, msg, "")
+	}
+
+	return err
+}
+
+func eventKey(msg types.VehicleEventMessage, step string) string {
+	if step == "" {
+		return "events:" + msg.IdempotencyKey
+	}
+
+	return "events:" + msg.IdempotencyKey + ":" + step
+}
+
+// eventHandled reports false for events without an idempotency key.
+func (s service) eventHandled(ctx context.Context, msg types.VehicleEventMessage, step string) bool {
+	if msg.IdempotencyKey == "" {
+		return false
+	}
+
+	_, handled, _ := s.results.Get(ctx, eventKey(msg, step))
+	return handled
+}
+
+func (s service) markEventHandled(ctx context.Context, msg types.VehicleEventMessage, step string) error {
+	if msg.IdempotencyKey == "" {
+		return nil
+	}
+
+	return s.results.Put(ctx, eventKey(msg, step), idempotency.Result{})
+}
+
+func (s service) OccupySlot(ctx context.Context, msg types.VehicleEventMessage) error {
+	occupyReq := types.OccupySlotRequest{
+		VehicleUUID:   msg.VehicleUUID,
+		StructureUUID: msg.StructureUUID,
+		StructureSlotRequest: types.StructureSlotRequest{
+			SlotNumber: msg.SlotNumber,
+			SlotType:   types.GetSlotTypeByVehicleType(msg.VehicleType),
+		},
+	}
+
+	err := s.integration.OccupySlotInTowerLeader(ctx, occupyReq, msg.IdempotencyKey)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		err = s.integration.OccupySlotInTowerLeader(ctx, occupyReq, msg.IdempotencyKey)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to occupy slot in tower leader: %w", err)
+	}
+
+	return nil
+}
+
+func (s service) ReleaseSlot(ctx context.Context, msg types.VehicleEventMessage) error {
 	releaseReq := types.ReleaseSlotRequest{
 		SlotNumber: msg.SlotNumber,
 		SlotType: types.GetSlotTypeByVehicleType(msg.VehicleType),
 	}
 
-	if err := s.integration.ReleaseSlot(ctx, msg.StructureUUID, msg.StructureType, releaseReq); err != nil {
-		return fmt.Errorf("failed to release slot in structure: %w", err)
+	// releasing the slot in the structure is not idempotent: a redelivered
+	// departure must not free it again once another vehicle may hold it
+	if !s.eventHandled(ctx, msg, structureReleaseStep) {
+		if err := s.integration.ReleaseSlot(ctx, msg.StructureUUID, msg.StructureType, releaseReq); err != nil {
+			return fmt.Errorf("failed to release slot in structure: %w", err)
+		}
+
+		if err := s.markEventHandled(ctx, msg, structureReleaseStep); err != nil {
+			log.Printf("[minion][consumer] failed to record structure release of vehicle %s with key %s: %v", msg.VehicleUUID.String(), msg.IdempotencyKey, err)
+		}
 	}
 
 	releaseLockReq := types.ReleaseSlotLockRequest{
@@ -129,9 +596,66 @@ func (s service) ReleaseSlot(ctx context.Context, data []byte) error {
 		},
 	}
 
-	if err := s.integration.ReleaseSlotLock(ctx, releaseLockReq); err != nil {
+	err := s.integration.ReleaseSlotLock(ctx, releaseLockReq, msg.IdempotencyKey)
+	if errors.Is(err, utils.ErrStaleTerm) && s.RefreshLeader(ctx) {
+		err = s.integration.ReleaseSlotLock(ctx, releaseLockReq, msg.IdempotencyKey)
+	}
+
+	if err != nil {
 		return fmt.Errorf("failed to release slot lock in tower leader: %w", err)
 	}
 
 	return nil
 }
+
+// AcceptNewLeader only adopts a known tower the election backend confirms for
+// the announced term.
+func (s service) AcceptNewLeader(ctx context.Context, req types.NewLeaderRequest) error {
+	if req.Term < config.Configuration.GetLeaderTerm() {
+		return fmt.Errorf("announced term %d is older than current term %d: %w", req.Term, config.Configuration.GetLeaderTerm(), utils.ErrStaleTerm)
+	}
+
+	if !s.isKnownTower(req.NewLeaderUUID) {
+		return fmt.Errorf("tower %s is not a known tower: %w", req.NewLeaderUUID.String(), utils.ErrUnverifiedLeader)
+	}
+
+	leaderUuid, term, err := s.elector.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current leader: %w: %w", utils.ErrUnverifiedLeader, err)
+	}
+
+	if leaderUuid != req.NewLeaderUUID || term != req.Term {
+		return fmt.Errorf("current leader is %s with term %d: %w", leaderUuid.String(), term, utils.ErrUnverifiedLeader)
+	}
+
+	config.Configuration.SetLeader(req.NewLeaderUUID, req.Term)
+	return nil
+}
+
+func (s service) isKnownTower(id types.UUID) bool {
+	if id == config.Configuration.GetId() {
+		return true
+	}
+
+	if consensus.Enabled() {
+		return slices.ContainsFunc(consensus.Replica.ListTowers(), func(tower types.Tower) bool { return tower.UUID == id })
+	}
+
+	_, ok := s.repository.GetTower(id)
+	return ok
+}
+
+func (s service) RefreshLeader(ctx context.Context) bool {
+	leaderUuid, term, err := s.elector.Leader(ctx)
+	if err != nil {
+		log.Printf("[minion][term] failed to refresh leader: %v", err)
+		return false
+	}
+
+	if !config.Configuration.SetLeaderIfNewer(leaderUuid, term) {
+		return false
+	}
+
+	log.Printf("[minion][term] leader refreshed to %s with term %d", leaderUuid.String(), term)
+	return true
+}
@@ -0,0 +1,46 @@
+package minion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ViniiSouza/maritime_flow/com_tower/pkg/types"
+)
+
+type stateLag struct {
+	mu    sync.Mutex
+	since time.Time
+}
+
+func newStateLag() *stateLag {
+	return &stateLag{}
+}
+
+func lagsBehind(held *types.StateVersion, leader *types.StateVersion) bool {
+	return leader != nil && (held == nil || held.Before(*leader))
+}
+
+// grace gives the propagations in flight the time to catch up
+func (l *stateLag) Observe(lagging bool, grace time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !lagging {
+		l.since = time.Time{}
+		return false
+	}
+
+	if l.since.IsZero() {
+		l.since = time.Now()
+		return false
+	}
+
+	return time.Since(l.since) >= grace
+}
+
+func (l *stateLag) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.since = time.Time{}
+}
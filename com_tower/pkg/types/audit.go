@@ -4,8 +4,12 @@ type ResultType string
 
 const (
 	// result types
-	AllowedResultType ResultType = "allowed"
-	DeniedResultType  ResultType = "denied"
+	AllowedResultType   ResultType = "allowed"
+	DeniedResultType    ResultType = "denied"
+	HeldResultType      ResultType = "held"
+	PreemptedResultType ResultType = "preempted"
+	DivergedResultType  ResultType = "diverged"
+	RepairedResultType  ResultType = "repaired"
 )
 
 type AuditRequest struct {
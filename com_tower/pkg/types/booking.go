@@ -0,0 +1,59 @@
+package types
+
+import "time"
+
+type BookingStatus string
+type BookingResultType string
+
+const (
+	// booking statuses
+	BookedBookingStatus    BookingStatus = "booked"
+	CancelledBookingStatus BookingStatus = "cancelled"
+	FulfilledBookingStatus BookingStatus = "fulfilled"
+
+	// booking result types
+	BookedBookingResultType  BookingResultType = "booked"
+	OverlapBookingResultType BookingResultType = "overlap"
+)
+
+type BookingRequest struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	VehicleType   VehicleType   `json:"vehicle_type"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	SlotNumber    int           `json:"slot_number"`
+	StartsAt      time.Time     `json:"starts_at"`
+	EndsAt        time.Time     `json:"ends_at"`
+}
+
+type Booking struct {
+	UUID          UUID          `json:"booking_uuid" db:"id"`
+	VehicleUUID   UUID          `json:"vehicle_uuid" db:"vehicle_id"`
+	StructureUUID UUID          `json:"structure_uuid" db:"structure_id"`
+	StructureType StructureType `json:"structure_type" db:"structure_type"`
+	SlotType      SlotType      `json:"slot_type" db:"slot_type"`
+	SlotNumber    int           `json:"slot_number" db:"slot_number"`
+	StartsAt      time.Time     `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time     `json:"ends_at" db:"ends_at"`
+	Status        BookingStatus `json:"status" db:"status"`
+}
+
+type BookingResponse struct {
+	Result  BookingResultType `json:"result"`
+	Booking *Booking          `json:"booking,omitempty"`
+}
+
+type CancelBookingRequest struct {
+	BookingUUID UUID `json:"booking_uuid"`
+}
+
+// BookingsQuery From defaults to now.
+type BookingsQuery struct {
+	StructureUUID UUID       `form:"structure_uuid"`
+	From          *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type BookingsResponse struct {
+	Bookings []Booking `json:"bookings"`
+}
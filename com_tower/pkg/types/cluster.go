@@ -0,0 +1,34 @@
+package types
+
+import "time"
+
+type ClusterStatus struct {
+	TowerUUID         UUID          `json:"tower_uuid"`
+	Role              string        `json:"role"`
+	LeaderUUID        UUID          `json:"leader_uuid"`
+	LeaderSince       time.Time     `json:"leader_since"`
+	Term              int64         `json:"term"`
+	LastHeartbeatAt   *time.Time    `json:"last_heartbeat_at,omitempty"`
+	HeartbeatFailures int           `json:"heartbeat_failures"`
+	Towers            []Tower       `json:"towers"`
+	TowersSyncedAt    *time.Time    `json:"towers_synced_at,omitempty"`
+	TowersVersion     *StateVersion `json:"towers_version,omitempty"`
+	StructuresVersion *StateVersion `json:"structures_version,omitempty"`
+	StateStale        bool          `json:"state_stale"`
+	StateSavedAt      *time.Time    `json:"state_saved_at,omitempty"`
+}
+
+type ClusterMember struct {
+	TowerUUID      UUID           `json:"tower_uuid"`
+	Reachable      bool           `json:"reachable"`
+	AgreesOnLeader bool           `json:"agrees_on_leader"`
+	Status         *ClusterStatus `json:"status,omitempty"`
+}
+
+// ClusterView is read-only when the leader does not reach a quorum of towers.
+type ClusterView struct {
+	ClusterStatus
+	Members    []ClusterMember `json:"members"`
+	SplitBrain bool            `json:"split_brain"`
+	ReadOnly   bool            `json:"read_only"`
+}
@@ -5,15 +5,25 @@ var vehicleSlotMapping = map[VehicleType]SlotType{
 	ShipVehicleType:       DockSlotType,
 }
 
+var slotVehicleMapping = map[SlotType]VehicleType{
+	HelipadSlotType: HelicopterVehicleType,
+	DockSlotType:    ShipVehicleType,
+}
+
 var slotResultMapping = map[SlotState]ResultType{
 	FreeSlotState:  AllowedResultType,
 	InUseSlotState: DeniedResultType,
+	HoldSlotState:  HeldResultType,
 }
 
 func GetSlotTypeByVehicleType(vehicle VehicleType) SlotType {
 	return vehicleSlotMapping[vehicle]
 }
 
+func GetVehicleTypeBySlotType(slot SlotType) VehicleType {
+	return slotVehicleMapping[slot]
+}
+
 func GetResultTypeBySlotState(state SlotState) ResultType {
 	return slotResultMapping[state]
 }
@@ -0,0 +1,81 @@
+package types
+
+import "time"
+
+type SlotTransition string
+type AnalyticsFormat string
+
+const (
+	// slot transitions
+	AcquiredSlotTransition SlotTransition = "acquired"
+	ArrivedSlotTransition  SlotTransition = "arrived"
+	DepartedSlotTransition SlotTransition = "departed"
+	ReleasedSlotTransition SlotTransition = "released"
+
+	// analytics formats
+	JSONAnalyticsFormat AnalyticsFormat = "json"
+	CSVAnalyticsFormat  AnalyticsFormat = "csv"
+)
+
+type SlotHistoryEntry struct {
+	VehicleUUID   UUID           `json:"vehicle_uuid"`
+	StructureUUID UUID           `json:"structure_uuid"`
+	SlotType      SlotType       `json:"slot_type"`
+	SlotNumber    int            `json:"slot_number"`
+	Transition    SlotTransition `json:"transition"`
+}
+
+// SlotVisit ArrivedAt is unset for vehicles that never arrived, and EndedAt
+// while the vehicle still holds the slot.
+type SlotVisit struct {
+	VehicleUUID UUID            `db:"vehicle_id"`
+	SlotType    SlotType        `db:"slot_type"`
+	SlotNumber  int             `db:"slot_number"`
+	AcquiredAt  time.Time       `db:"acquired_at"`
+	ArrivedAt   *time.Time      `db:"arrived_at"`
+	EndedAt     *time.Time      `db:"ended_at"`
+	EndedBy     *SlotTransition `db:"ended_by"`
+}
+
+// AnalyticsQuery From and To default to the week before now.
+type AnalyticsQuery struct {
+	StructureUUID UUID            `form:"structure_uuid"`
+	From          *time.Time      `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            *time.Time      `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Format        AnalyticsFormat `form:"format"`
+}
+
+type SlotUtilization struct {
+	SlotType        SlotType `json:"slot_type"`
+	Slots           int      `json:"slots"`
+	Visits          int      `json:"visits"`
+	HeldSeconds     float64  `json:"held_seconds"`
+	OccupiedSeconds float64  `json:"occupied_seconds"`
+	HeldRatio       float64  `json:"held_ratio"`
+	OccupiedRatio   float64  `json:"occupied_ratio"`
+}
+
+type UtilizationResponse struct {
+	StructureUUID UUID              `json:"structure_uuid"`
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	Utilization   []SlotUtilization `json:"utilization"`
+}
+
+type DurationStats struct {
+	SlotType    SlotType `json:"slot_type"`
+	Count       int      `json:"count"`
+	MeanSeconds float64  `json:"mean_seconds"`
+	P50Seconds  float64  `json:"p50_seconds"`
+	P90Seconds  float64  `json:"p90_seconds"`
+	P95Seconds  float64  `json:"p95_seconds"`
+	P99Seconds  float64  `json:"p99_seconds"`
+	MaxSeconds  float64  `json:"max_seconds"`
+}
+
+type DurationStatsResponse struct {
+	StructureUUID UUID            `json:"structure_uuid"`
+	From          time.Time       `json:"from"`
+	To            time.Time       `json:"to"`
+	Stats         []DurationStats `json:"stats"`
+}
@@ -1,21 +1,72 @@
 package types
 
+import "bytes"
+
 type Role int
+type ConsensusMode string
 
 const (
 	Leader Role = iota
 	Minion
 )
 
+func (r Role) String() string {
+	if r == Leader {
+		return "leader"
+	}
+
+	return "minion"
+}
+
+const (
+	// consensus modes
+	LockConsensusMode ConsensusMode = "lock"
+	RaftConsensusMode ConsensusMode = "raft"
+)
+
+type Candidate struct {
+	UUID      UUID  `json:"tower_uuid"`
+	Priority  int   `json:"priority"`
+	StartedAt int64 `json:"started_at"`
+}
+
+// Outranks compares priority, then uptime, then UUID, so both sides of a
+// comparison always agree on the winner.
+func (c Candidate) Outranks(other Candidate) bool {
+	if c.Priority != other.Priority {
+		return c.Priority > other.Priority
+	}
+
+	if c.StartedAt != other.StartedAt {
+		return c.StartedAt < other.StartedAt
+	}
+
+	return bytes.Compare(c.UUID[:], other.UUID[:]) > 0
+}
+
 type ElectionRequest struct {
-	CandidateUptime float64 `json:"candidate_uptime"`
+	Candidate Candidate `json:"candidate"`
 }
 
 type ElectionResponse struct {
-	Uptime          float64 `json:"uptime"`
-	HasHigherUptime bool    `json:"has_higher_uptime"`
+	Tower    Candidate `json:"tower"`
+	Outranks bool      `json:"outranks"`
+}
+
+type PreVoteRequest struct {
+	LeaderUUID UUID  `json:"leader_uuid"`
+	Term       int64 `json:"term"`
+}
+
+type PreVoteResponse struct {
+	LeaderReachable bool `json:"leader_reachable"`
 }
 
 type NewLeaderRequest struct {
-	NewLeaderUUID UUID `json:"new_leader_uuid"`
+	NewLeaderUUID UUID  `json:"new_leader_uuid"`
+	Term          int64 `json:"term"`
+}
+
+type TransferLeadershipRequest struct {
+	TowerUUID UUID `json:"tower_uuid"`
 }
@@ -0,0 +1,47 @@
+package types
+
+import "time"
+
+type ReconcilePolicy string
+type DivergenceType string
+
+const (
+	// reconcile policies
+	ReportReconcilePolicy ReconcilePolicy = "report"
+	RepairReconcilePolicy ReconcilePolicy = "repair"
+
+	// divergence types
+	FreeInStructureDivergenceType DivergenceType = "free_in_structure"
+	UnheldInLeaderDivergenceType  DivergenceType = "unheld_in_leader"
+)
+
+type StructureSlotState struct {
+	SlotNumber int       `json:"slot_number"`
+	SlotType   SlotType  `json:"slot_type"`
+	State      SlotState `json:"state"`
+}
+
+type StructureSlotsResponse struct {
+	Slots []StructureSlotState `json:"slots"`
+}
+
+type Divergence struct {
+	Type          DivergenceType `json:"type"`
+	StructureUUID UUID           `json:"structure_uuid"`
+	StructureType StructureType  `json:"structure_type"`
+	SlotType      SlotType       `json:"slot_type"`
+	SlotNumber    int            `json:"slot_number"`
+	VehicleUUID   *UUID          `json:"vehicle_uuid,omitempty"`
+	DetectedAt    time.Time      `json:"detected_at"`
+}
+
+type ReconciliationStatus struct {
+	Policy            ReconcilePolicy `json:"policy"`
+	Runs              int             `json:"runs"`
+	LastRunAt         *time.Time      `json:"last_run_at,omitempty"`
+	DivergencesFound  int             `json:"divergences_found"`
+	Repaired          int             `json:"repaired"`
+	RepairFailures    int             `json:"repair_failures"`
+	StructureFailures int             `json:"structure_failures"`
+	Divergences       []Divergence    `json:"divergences"`
+}
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+type SagaStep string
+
+const (
+	// saga steps
+	ReservedSagaStep     SagaStep = "reserved"
+	CompensatingSagaStep SagaStep = "compensating"
+	InDoubtSagaStep      SagaStep = "in_doubt"
+)
+
+// SlotSaga in doubt was still reserved when the tower stopped: the leader may
+// or may not have acquired its slot.
+type SlotSaga struct {
+	ID            string        `json:"saga_id"`
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	SlotType      SlotType      `json:"slot_type"`
+	SlotNumber    int           `json:"slot_number"`
+	Step          SagaStep      `json:"step"`
+	StartedAt     time.Time     `json:"started_at"`
+	Attempts      int           `json:"attempts"`
+	LastError     string        `json:"last_error,omitempty"`
+}
+
+type SagasResponse struct {
+	Sagas []SlotSaga `json:"sagas"`
+}
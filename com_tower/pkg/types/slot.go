@@ -2,6 +2,7 @@ package types
 
 type SlotType string
 type SlotState string
+type SlotPriority string
 type AcquireSlotResultType string
 
 const (
@@ -12,32 +13,74 @@ const (
 	// slot states
 	FreeSlotState  SlotState = "free"
 	InUseSlotState SlotState = "in_use"
+	HoldSlotState  SlotState = "hold"
+
+	// slot priorities
+	RoutineSlotPriority   SlotPriority = "routine"
+	MedicalSlotPriority   SlotPriority = "medical"
+	EmergencySlotPriority SlotPriority = "emergency"
 
 	// acquire slot result types
 	AcquiredAcquireSlotResultType    AcquireSlotResultType = "acquired"
 	UnavailableAcquireSlotResultType AcquireSlotResultType = "unavailable"
+	ConflictAcquireSlotResultType    AcquireSlotResultType = "conflict"
+	WaitlistedAcquireSlotResultType  AcquireSlotResultType = "waitlisted"
 )
 
+// requests without a priority are routine
+func (p SlotPriority) IsValid() bool {
+	switch p {
+	case "", RoutineSlotPriority, MedicalSlotPriority, EmergencySlotPriority:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p SlotPriority) JumpsWaitlist() bool {
+	return p == MedicalSlotPriority || p == EmergencySlotPriority
+}
+
 type StructureSlotRequest struct {
 	SlotNumber int      `json:"slot_number"`
 	SlotType   SlotType `json:"slot_type"`
 }
 
 type SlotRequest struct {
-	VehicleUUID   UUID              `json:"vehicle_uuid"`
-	VehicleType   VehicleType     `json:"vehicle_type"`
-	StructureUUID UUID              `json:"structure_uuid"`
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	VehicleType   VehicleType   `json:"vehicle_type"`
+	StructureUUID UUID          `json:"structure_uuid"`
 	StructureType StructureType `json:"structure_type"`
+	Priority      SlotPriority  `json:"priority,omitempty"`
 	StructureSlotRequest
 }
 
 type SlotResponse struct {
-	State SlotState `json:"state"`
+	State      SlotState `json:"state"`
+	SlotNumber int       `json:"slot_number,omitempty"`
+}
+
+type AssignSlotRequest struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	VehicleType   VehicleType   `json:"vehicle_type"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	Priority      SlotPriority  `json:"priority,omitempty"`
+}
+
+type FreeSlotsRequest struct {
+	StructureUUID UUID     `json:"structure_uuid"`
+	SlotType      SlotType `json:"slot_type"`
+}
+
+type FreeSlotsResponse struct {
+	SlotNumbers []int `json:"slot_numbers"`
 }
 
 type AcquireSlotRequest struct {
-	VehicleUUID   UUID `json:"vehicle_uuid"`
-	StructureUUID UUID `json:"structure_uuid"`
+	VehicleUUID   UUID         `json:"vehicle_uuid"`
+	StructureUUID UUID         `json:"structure_uuid"`
+	Priority      SlotPriority `json:"priority,omitempty"`
 	StructureSlotRequest
 }
 
@@ -48,3 +91,23 @@ type AcquireSlotResponse struct {
 type ReleaseSlotRequest StructureSlotRequest
 
 type ReleaseSlotLockRequest AcquireSlotRequest
+
+type OccupySlotRequest AcquireSlotRequest
+
+type SlotReservation struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid" db:"vehicle_id"`
+	StructureUUID UUID          `json:"structure_uuid" db:"structure_id"`
+	StructureType StructureType `json:"structure_type" db:"structure_type"`
+	SlotType      SlotType      `json:"slot_type" db:"slot_type"`
+	SlotNumber    int           `json:"slot_number" db:"slot_number"`
+}
+
+type PreemptionMessage struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	SlotType      SlotType      `json:"slot_type"`
+	SlotNumber    int           `json:"slot_number"`
+	PreemptedBy   UUID          `json:"preempted_by"`
+	Timestamp     int           `json:"timestamp"`
+}
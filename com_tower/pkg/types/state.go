@@ -0,0 +1,48 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// StateVersion Seq only compares within an epoch, and epochs within a term: a
+// leader restarted in the same term starts a newer epoch.
+type StateVersion struct {
+	Term  int64 `json:"term"`
+	Epoch int64 `json:"epoch"`
+	Seq   int64 `json:"seq"`
+}
+
+func (v StateVersion) Before(other StateVersion) bool {
+	if v.Term != other.Term {
+		return v.Term < other.Term
+	}
+
+	if v.Epoch != other.Epoch {
+		return v.Epoch < other.Epoch
+	}
+
+	return v.Seq < other.Seq
+}
+
+func (v StateVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Term, v.Epoch, v.Seq)
+}
+
+type StateSnapshot struct {
+	Version    StateVersion `json:"version"`
+	Towers     []Tower      `json:"towers"`
+	Structures Structures   `json:"structures"`
+}
+
+type TowerHealthResponse struct {
+	Version *StateVersion `json:"version,omitempty"`
+}
+
+type CacheSnapshot struct {
+	SavedAt           time.Time     `json:"saved_at"`
+	Towers            []Tower       `json:"towers"`
+	Structures        Structures    `json:"structures"`
+	TowersVersion     *StateVersion `json:"towers_version,omitempty"`
+	StructuresVersion *StateVersion `json:"structures_version,omitempty"`
+}
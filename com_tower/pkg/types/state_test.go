@@ -0,0 +1,27 @@
+package types
+
+import "testing"
+
+func TestStateVersionBefore(t *testing.T) {
+	tests := []struct {
+		name    string
+		version StateVersion
+		other   StateVersion
+		before  bool
+	}{
+		{name: "lower seq in the same epoch", version: StateVersion{Term: 1, Epoch: 10, Seq: 1}, other: StateVersion{Term: 1, Epoch: 10, Seq: 2}, before: true},
+		{name: "same version", version: StateVersion{Term: 1, Epoch: 10, Seq: 2}, other: StateVersion{Term: 1, Epoch: 10, Seq: 2}, before: false},
+		{name: "higher seq of an older epoch", version: StateVersion{Term: 1, Epoch: 10, Seq: 50}, other: StateVersion{Term: 1, Epoch: 20, Seq: 1}, before: true},
+		{name: "lower seq of a newer epoch", version: StateVersion{Term: 1, Epoch: 20, Seq: 1}, other: StateVersion{Term: 1, Epoch: 10, Seq: 50}, before: false},
+		{name: "newer epoch of an older term", version: StateVersion{Term: 1, Epoch: 20, Seq: 50}, other: StateVersion{Term: 2, Epoch: 10, Seq: 1}, before: true},
+		{name: "older epoch of a newer term", version: StateVersion{Term: 2, Epoch: 10, Seq: 1}, other: StateVersion{Term: 1, Epoch: 20, Seq: 50}, before: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if before := tt.version.Before(tt.other); before != tt.before {
+				t.Fatalf("%s.Before(%s) = %t, want %t", tt.version.String(), tt.other.String(), before, tt.before)
+			}
+		})
+	}
+}
@@ -32,3 +32,12 @@ type Structures struct {
 	Platforms []Platform `json:"platforms"`
 	Centrals  []Central  `json:"centrals"`
 }
+
+// StructuresPayload only carries the changes since Base when it is set.
+type StructuresPayload struct {
+	Structures
+	RemovedPlatforms []UUID        `json:"removed_platforms,omitempty"`
+	RemovedCentrals  []UUID        `json:"removed_centrals,omitempty"`
+	Version          *StateVersion `json:"version,omitempty"`
+	Base             *StateVersion `json:"base,omitempty"`
+}
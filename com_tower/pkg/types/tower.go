@@ -1,15 +1,39 @@
 package types
 
+import "time"
+
 type Tower struct {
 	UUID      UUID    `json:"tower_uuid" db:"id"`
 	Latitude  float64 `json:"latitude" db:"latitude"`
 	Longitude float64 `json:"longitude" db:"longitude"`
 }
 
+type TowerPropagation struct {
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+type TowerStatus struct {
+	Tower
+	Propagation *TowerPropagation `json:"propagation,omitempty"`
+}
+
+type TowerStatusesPayload struct {
+	Towers []TowerStatus `json:"towers"`
+}
+
 type TowerHealthRequest struct {
-	Id UUID `json:"tower_id"`
+	Id                UUID          `json:"tower_id"`
+	TowersVersion     *StateVersion `json:"towers_version,omitempty"`
+	StructuresVersion *StateVersion `json:"structures_version,omitempty"`
 }
 
+// TowersPayload only carries the changes since Base when it is set.
 type TowersPayload struct {
-	Towers []Tower `json:"towers"`
+	Towers  []Tower       `json:"towers"`
+	Removed []UUID        `json:"removed,omitempty"`
+	Version *StateVersion `json:"version,omitempty"`
+	Base    *StateVersion `json:"base,omitempty"`
 }
@@ -17,6 +17,15 @@ func (u *UUID) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+func (u *UUID) UnmarshalParam(param string) error {
+	id, err := uuid.Parse(param)
+	if err != nil {
+		return err
+	}
+	*u = UUID(id)
+	return nil
+}
+
 func (u UUID) MarshalJSON() ([]byte, error) {
 	return fmt.Appendf(nil, "\"%s\"", uuid.UUID(u).String()), nil
 }
@@ -13,13 +13,15 @@ const (
 	ArrivalEventType   EventType = "arrived"
 )
 
+// VehicleEventMessage events carrying an IdempotencyKey are handled once.
 type VehicleEventMessage struct {
-	VehicleType   VehicleType   `json:"vehicle_type"`
-	VehicleUUID   UUID          `json:"vehicle_uuid"`
-	StructureType StructureType `json:"structure_type"`
-	StructureUUID UUID          `json:"structure_uuid"`
-	Timestamp     int           `json:"timestamp"`
-	Event         EventType     `json:"event"`
-	SlotNumber    int           `json:"slot_number"`
-	TowerUUID     UUID          `json:"tower_id"`
+	VehicleType    VehicleType   `json:"vehicle_type"`
+	VehicleUUID    UUID          `json:"vehicle_uuid"`
+	StructureType  StructureType `json:"structure_type"`
+	StructureUUID  UUID          `json:"structure_uuid"`
+	Timestamp      int           `json:"timestamp"`
+	Event          EventType     `json:"event"`
+	SlotNumber     int           `json:"slot_number"`
+	TowerUUID      UUID          `json:"tower_id"`
+	IdempotencyKey string        `json:"idempotency_key,omitempty"`
 }
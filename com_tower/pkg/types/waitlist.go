@@ -0,0 +1,40 @@
+package types
+
+import "time"
+
+// WaitlistRequest vehicles are served by priority, then in arrival order.
+type WaitlistRequest struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	VehicleType   VehicleType   `json:"vehicle_type"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	Priority      int           `json:"priority"`
+}
+
+type LeaveWaitlistRequest struct {
+	VehicleUUID UUID `json:"vehicle_uuid"`
+}
+
+type WaitlistEntry struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid" db:"vehicle_id"`
+	StructureUUID UUID          `json:"structure_uuid" db:"structure_id"`
+	StructureType StructureType `json:"structure_type" db:"structure_type"`
+	SlotType      SlotType      `json:"slot_type" db:"slot_type"`
+	Priority      int           `json:"priority" db:"priority"`
+	EnqueuedAt    time.Time     `json:"enqueued_at" db:"enqueued_at"`
+}
+
+type WaitlistResponse struct {
+	Position             int  `json:"position"`
+	EstimatedWaitSeconds *int `json:"estimated_wait_seconds,omitempty"`
+}
+
+type WaitlistGrantMessage struct {
+	VehicleUUID   UUID          `json:"vehicle_uuid"`
+	StructureUUID UUID          `json:"structure_uuid"`
+	StructureType StructureType `json:"structure_type"`
+	SlotType      SlotType      `json:"slot_type"`
+	SlotNumber    int           `json:"slot_number"`
+	ReservedUntil int           `json:"reserved_until"`
+	Timestamp     int           `json:"timestamp"`
+}
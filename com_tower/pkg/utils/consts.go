@@ -1,5 +1,7 @@
 package utils
 
+import "time"
+
 const (
 	// envs
 	TowerIdEnv              = "TOWER_ID"
@@ -15,13 +17,50 @@ const (
 	HeartbeatTimeoutEnv     = "HEARTBEAT_TIMEOUT"
 	RenewLockIntervalEnv    = "RENEW_LOCK_INTERVAL"
 	RenewLockTimeoutEnv     = "RENEW_LOCK_TIMEOUT"
+	QuorumSizeEnv           = "QUORUM_SIZE"
+	ReservationTTLEnv       = "RESERVATION_TTL"
+	IdempotencyTTLEnv       = "IDEMPOTENCY_TTL"
+	DataDirEnv              = "DATA_DIR"
+	CacheSnapshotEnv        = "CACHE_SNAPSHOT"
+	ReconcileIntervalEnv    = "RECONCILE_INTERVAL"
+	ReconcilePolicyEnv      = "RECONCILE_POLICY"
 	BaseDnsEnv              = "BASE_DNS"
+	AdminTokenEnv           = "ADMIN_TOKEN"
+	ElectionPriorityEnv     = "ELECTION_PRIORITY"
+	ConsensusModeEnv        = "CONSENSUS_MODE"
+	RaftPortEnv             = "RAFT_PORT"
+	RaftDirEnv              = "RAFT_DIR"
+	RaftPeersEnv            = "RAFT_PEERS"
 	EmailHostEnv            = "EMAIL_HOST"
 	EmailPortEnv            = "EMAIL_PORT"
 	EmailUserEnv            = "EMAIL_USER"
 	EmailPasswordEnv        = "EMAIL_PASSWORD"
 	EmailRecipientsEnv      = "EMAIL_RECIPIENTS"
 
+	// propagation envs
+	PropagationParallelismEnv = "PROPAGATION_PARALLELISM"
+	PropagationTimeoutEnv     = "PROPAGATION_TIMEOUT"
+	PropagationRetriesEnv     = "PROPAGATION_RETRIES"
+
+	// defaults
+	DefaultReservationTTL    = 10 * time.Minute
+	DefaultIdempotencyTTL    = 24 * time.Hour
+	DefaultDataDir           = "data"
+	DefaultReconcileInterval = time.Minute
+
+	// propagation defaults
+	DefaultPropagationParallelism = 8
+	DefaultPropagationTimeout     = 5 * time.Second
+	DefaultPropagationRetries     = 2
+
+	// headers
+	LeaderTermHeader         = "X-Leader-Term"
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	StateStaleHeader         = "X-State-Stale"
+	StateSavedAtHeader       = "X-State-Saved-At"
+	AdminTokenHeader         = "X-Admin-Token"
+
 	// email templates
 	EmailSubjectTemplate = "[CRITICAL] %s %s down!"
 	EmailBodyTemplate = "Alert!\nTower %s has identified that %s %s is down!\nPlease check the status of the structure right now!"
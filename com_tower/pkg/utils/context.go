@@ -12,6 +12,20 @@ func SetContextAndExecJSONWithErrorResponse(c *gin.Context, err error) {
 	switch {
 	case errors.Is(err, ErrInvalidInput):
 		httpStatus = http.StatusBadRequest
+	case errors.Is(err, ErrStaleTerm), errors.Is(err, ErrIdempotencyKeyBusy):
+		httpStatus = http.StatusConflict
+	case errors.Is(err, ErrUnauthorized):
+		httpStatus = http.StatusUnauthorized
+	case errors.Is(err, ErrUnverifiedLeader):
+		httpStatus = http.StatusForbidden
+	case errors.Is(err, ErrLeaseExpired), errors.Is(err, ErrTransferInProgress), errors.Is(err, ErrNoQuorum), errors.Is(err, ErrStateNotSynced):
+		httpStatus = http.StatusServiceUnavailable
+	case errors.Is(err, ErrRaftUnsupported):
+		httpStatus = http.StatusNotImplemented
+	case errors.Is(err, ErrOutOfOrderState):
+		httpStatus = http.StatusPreconditionFailed
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		httpStatus = http.StatusUnprocessableEntity
 	default:
 		httpStatus = http.StatusInternalServerError
 	}
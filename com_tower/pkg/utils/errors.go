@@ -37,4 +37,17 @@ var (
 	ErrInvalidUUID          = errors.New("invalid or bad formated uuid")
 	ErrLeaderUnreachable    = errors.New("failed to communicate with leader")
 	ErrStructureUnreachable = errors.New("failed to communicate with structure")
+	ErrStaleTerm            = errors.New("stale leader term")
+	ErrLockLost             = errors.New("tower lock is no longer held")
+	ErrLeaseExpired         = errors.New("leader lease expired")
+	ErrTransferInProgress   = errors.New("leadership transfer in progress")
+	ErrUnverifiedLeader     = errors.New("leader claim could not be verified")
+	ErrNoQuorum             = errors.New("leader does not reach a quorum of towers: read-only mode")
+	ErrLeaderReadOnly       = errors.New("leader is not granting slots")
+	ErrRaftUnsupported      = errors.New("not supported in raft consensus mode")
+	ErrOutOfOrderState      = errors.New("state payload does not follow the state held")
+	ErrStateNotSynced       = errors.New("towers and structures were not synced from the leader yet")
+	ErrUnauthorized         = errors.New("missing or invalid admin token")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with another request")
+	ErrIdempotencyKeyBusy   = errors.New("a request with the same idempotency key is still running")
 )
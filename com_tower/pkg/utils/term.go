@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func SetLeaderTermHeader(req *http.Request, term int64) {
+	req.Header.Set(LeaderTermHeader, strconv.FormatInt(term, 10))
+}
+
+// GetLeaderTermFromHeader returns zero, older than any valid term, when the
+// header is missing or malformed.
+func GetLeaderTermFromHeader(header http.Header) int64 {
+	term, err := strconv.ParseInt(header.Get(LeaderTermHeader), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return term
+}
@@ -87,8 +87,8 @@ public class RabbitMQService : IDisposable
             var body = Encoding.UTF8.GetBytes(json);
 
             _channel.BasicPublish(
-                exchange: "",
-                routingKey: _auditQueue,
+                exchange: _eventsExchange,
+                routingKey: "",
                 basicProperties: null,
                 body: body
             );
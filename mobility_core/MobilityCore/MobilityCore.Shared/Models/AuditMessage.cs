@@ -27,5 +27,8 @@ public class AuditMessage
 
     [JsonPropertyName("tower_id")]
     public string TowerId { get; set; } = string.Empty;
+
+    [JsonPropertyName("idempotency_key")]
+    public string IdempotencyKey { get; set; } = Guid.NewGuid().ToString();
 }
 
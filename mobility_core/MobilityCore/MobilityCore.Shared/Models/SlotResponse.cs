@@ -5,6 +5,6 @@ namespace MobilityCore.Shared.Models;
 public class SlotResponse
 {
     [JsonPropertyName("state")]
-    public string State { get; set; } = string.Empty; // "free" | "in_use"
+    public string State { get; set; } = string.Empty; // "free" | "in_use" | "hold"
 }
 
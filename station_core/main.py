@@ -52,6 +52,16 @@ class SlotResponse(BaseModel):
     state: SlotState 
 
 
+class SlotStateResponse(BaseModel):
+    slot_number: int
+    slot_type: SlotType
+    state: SlotState
+
+
+class SlotsResponse(BaseModel):
+    slots: List[SlotStateResponse]
+
+
 @app.get("/")
 async def root():
     """Endpoint raiz para verificação de saúde do serviço"""
@@ -61,6 +71,24 @@ async def root():
     }
 
 
+@app.get("/slots", response_model=SlotsResponse)
+async def list_slots():
+    """
+    Endpoint para listar o estado de todos os slots
+    Chamado pela Torre (T) líder para reconciliar o estado dos slots
+    com o estado que ela mantém
+    """
+    return SlotsResponse(slots=[
+        SlotStateResponse(
+            slot_number=number,
+            slot_type=slot_type,
+            state=SlotState.FREE if free else SlotState.IN_USE
+        )
+        for slot_type, states in slots.items()
+        for number, free in enumerate(states, start=1)
+    ])
+
+
 @app.post("/slots", response_model=SlotResponse)
 async def acquire_slot(request: SlotRequest):
     """